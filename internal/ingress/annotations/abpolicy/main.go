@@ -0,0 +1,1365 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abpolicy
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	// MatchExact selects a backend when the header value is equal to the configured one
+	MatchExact = "exact"
+	// MatchRegex selects a backend when the header value matches the configured regular expression
+	MatchRegex = "regex"
+)
+
+const (
+	// MatchOrderFirst selects the first backend matching the request
+	MatchOrderFirst = "first"
+	// MatchOrderLast selects the last backend matching the request
+	MatchOrderLast = "last"
+)
+
+var (
+	// ErrInvalidExpiration the expiration of the policy is not a RFC 3339 time
+	ErrInvalidExpiration = errors.New("invalid abpolicy expiration")
+	// ErrInvalidHost the host of the policy is not a valid DNS name
+	ErrInvalidHost = errors.New("invalid abpolicy host")
+	// ErrInvalidPath the paths of the policy are missing, not absolute or conflicting
+	ErrInvalidPath = errors.New("invalid abpolicy path")
+	// ErrInvalidType the type of the policy is missing or not supported
+	ErrInvalidType = errors.New("invalid abpolicy type")
+	// ErrInvalidHeader a header name of the policy is not a RFC 7230 token
+	ErrInvalidHeader = errors.New("invalid abpolicy header")
+	// ErrInvalidMatch the match mode or the match order of the policy is not supported
+	ErrInvalidMatch = errors.New("invalid abpolicy match")
+	// ErrInvalidWeight a weight or percentage of the policy is out of range
+	ErrInvalidWeight = errors.New("invalid abpolicy weight")
+	// ErrInvalidBackends the backends of the policy cannot be decoded or are not valid
+	ErrInvalidBackends = errors.New("invalid abpolicy backends")
+	// ErrEmptyBackends the policy is enabled without backends
+	ErrEmptyBackends = errors.New("abpolicy without backends")
+	// ErrUnknownService a backend of the policy is not a service of the ingress
+	ErrUnknownService = errors.New("unknown abpolicy service")
+	// ErrInvalidPolicy the combination of fields of the policy is not valid
+	ErrInvalidPolicy = errors.New("invalid abpolicy")
+)
+
+// ValidationError is returned when the annotations of a policy are not valid.
+// Its message is the one of the annotation error it wraps, while Kind is one of
+// the Err* variables of the package describing the failure.
+type ValidationError struct {
+	Kind error
+	Err  error
+}
+
+func (e ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the annotation error
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true when target is the kind of the error
+func (e ValidationError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// newValidationError returns a ValidationError of the given kind
+func newValidationError(kind, err error) error {
+	return ValidationError{Kind: kind, Err: err}
+}
+
+// PolicyType defines how a backend of the policy is selected
+type PolicyType string
+
+const (
+	// PolicyTypeHeader selects a backend using the value of a request header
+	PolicyTypeHeader PolicyType = "header"
+	// PolicyTypeWeight splits the requests between the backends by weight
+	PolicyTypeWeight PolicyType = "weight"
+	// PolicyTypeCookie selects a backend using the value of a cookie
+	PolicyTypeCookie PolicyType = "cookie"
+	// PolicyTypeQuery selects a backend using the value of a query parameter
+	PolicyTypeQuery PolicyType = "query"
+	// PolicyTypePercentage splits the requests between two backends by percentage
+	PolicyTypePercentage PolicyType = "percentage"
+	// PolicyTypeMethod selects a backend using the HTTP method of the request
+	PolicyTypeMethod PolicyType = "method"
+	// PolicyTypeCIDR selects a backend using the client address of the request
+	PolicyTypeCIDR PolicyType = "cidr"
+	// PolicyTypeMirror sends a copy of the requests to a backend, ignoring its responses
+	PolicyTypeMirror PolicyType = "mirror"
+)
+
+// SupportedTypes contains the valid values of the abpolicy-type annotation
+var SupportedTypes = []string{
+	string(PolicyTypeHeader),
+	string(PolicyTypeWeight),
+	string(PolicyTypeCookie),
+	string(PolicyTypeQuery),
+	string(PolicyTypePercentage),
+	string(PolicyTypeMethod),
+	string(PolicyTypeCIDR),
+	string(PolicyTypeMirror),
+}
+
+// supportedMethods contains the HTTP methods accepted by method policies
+var supportedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+type abpolicy struct {
+	r resolver.Resolver
+}
+
+// Config returns the configuration rules for setting up an A/B policy
+type Config struct {
+	Enabled bool
+	// Host is always lower-cased, so it can be compared with the normalized request host
+	Host string
+	Path string
+	// Paths allows to apply the policy to several paths. It cannot be used with Path.
+	Paths []string
+	// ExcludePaths contains path prefixes where the policy is not applied,
+	// even if they are also prefixed by one of the paths of the policy
+	ExcludePaths []string
+	Type         PolicyType
+	Header       string
+	// QueryParam is the name of the query parameter used by query policies
+	QueryParam string
+	Match      string
+	// MatchOrder decides which backend is selected when several of them match a request.
+	// An empty value is equivalent to MatchOrderFirst.
+	MatchOrder string
+	// MatchSNI compares the host of the policy with the TLS server name instead of the Host header
+	MatchSNI bool
+	// Weight is the percentage (0-100) of the requests evaluated by the policy.
+	// The remaining requests are sent to the backend of the ingress.
+	Weight float32
+	// Sticky requests weight policies to always select the same backend for a client
+	Sticky bool
+	// HashBy is the key hashed to select the backend of sticky weight policies,
+	// i.e. remote_addr, cookie or the name of a header
+	HashBy string
+	// Percentage (0-100) of the requests sent to the first backend of percentage policies.
+	// The second backend receives the remaining requests.
+	Percentage int
+	Backends   []*Backend
+	// DefaultBackend is the service receiving the requests not matching any backend.
+	// When it is empty the requests are sent to the backend of the ingress.
+	DefaultBackend string
+	// MirrorBackend is the service receiving a copy of the requests of mirror policies
+	MirrorBackend string
+	// DryRun evaluates the policy and logs the selected backend
+	// while the requests keep being sent to the backend of the ingress
+	DryRun bool
+	// ExpiresAt is the time after which the policy is considered disabled.
+	// The zero value means the policy does not expire.
+	ExpiresAt time.Time
+	// Warnings contains problems of the configuration that do not prevent its use
+	Warnings []string
+}
+
+// Backend defines a service that receives the requests matching a rule of the A/B policy
+type Backend struct {
+	Name   string `json:"name,omitempty"`
+	Header string `json:"header,omitempty"`
+	// Headers contains a list of values where any of them selects the backend.
+	// When it is not empty Header is ignored.
+	Headers []string `json:"headers,omitempty"`
+	Weight  int      `json:"weight,omitempty"`
+	// Port of the service receiving the requests. Zero means the default port of the service.
+	Port int `json:"port,omitempty"`
+	// Negate selects the backend when the request does not match its values
+	Negate bool `json:"negate,omitempty"`
+	// Methods contains the upper-cased HTTP methods parsed from the values of method policies.
+	// It is filled by Parse and cannot be set in the abpolicy-backends annotation.
+	Methods []string `json:"methods,omitempty"`
+	// CIDRs contains the client address ranges parsed from the values of cidr policies.
+	// It is filled by Parse and cannot be set in the abpolicy-backends annotation.
+	CIDRs []string `json:"cidrs,omitempty"`
+	// PresenceOnly selects the backend when the header of the policy is present,
+	// regardless of its value. Only supported by header policies.
+	PresenceOnly bool `json:"presenceOnly,omitempty"`
+	// Path is a path prefix the request must also match to select the backend.
+	// When it is empty any path of the policy matches.
+	Path string `json:"path,omitempty"`
+	// SetHeaders contains the headers added to the requests sent to the backend,
+	// i.e. to let the service know the variant of the request
+	SetHeaders map[string]string `json:"setHeaders,omitempty"`
+}
+
+// HeaderValues returns the values that select the backend
+func (b *Backend) HeaderValues() []string {
+	if len(b.Headers) > 0 {
+		return b.Headers
+	}
+	if b.Header != "" {
+		return []string{b.Header}
+	}
+
+	return []string{}
+}
+
+// MatchesPath returns true when the path of a request satisfies the path condition
+// of the backend. The backend is selected only if its values also match the request.
+func (b *Backend) MatchesPath(path string) bool {
+	return b.Path == "" || strings.HasPrefix(path, b.Path)
+}
+
+// DefaultConfig returns the configuration of an ingress without A/B policy annotations:
+// a disabled policy where every field has its zero value and the list of backends is empty.
+// The defaults of the annotations (exact match, first match order and a weight of 100)
+// are applied by Parse only to the ingresses containing abpolicy annotations.
+func DefaultConfig() *Config {
+	return &Config{
+		Backends: []*Backend{},
+	}
+}
+
+// NewParser parses the ingress for A/B policy related annotations
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return abpolicy{r}
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.Host != c2.Host {
+		return false
+	}
+	if c1.Path != c2.Path {
+		return false
+	}
+	if len(c1.Paths) != len(c2.Paths) {
+		return false
+	}
+	for i := range c1.Paths {
+		if c1.Paths[i] != c2.Paths[i] {
+			return false
+		}
+	}
+	if len(c1.ExcludePaths) != len(c2.ExcludePaths) {
+		return false
+	}
+	for i := range c1.ExcludePaths {
+		if c1.ExcludePaths[i] != c2.ExcludePaths[i] {
+			return false
+		}
+	}
+	if c1.Type != c2.Type {
+		return false
+	}
+	if c1.Header != c2.Header {
+		return false
+	}
+	if c1.QueryParam != c2.QueryParam {
+		return false
+	}
+	if c1.matchMode() != c2.matchMode() {
+		return false
+	}
+	if c1.matchOrder() != c2.matchOrder() {
+		return false
+	}
+	if c1.MatchSNI != c2.MatchSNI {
+		return false
+	}
+	if c1.Weight != c2.Weight {
+		return false
+	}
+	if c1.Sticky != c2.Sticky {
+		return false
+	}
+	if c1.HashBy != c2.HashBy {
+		return false
+	}
+	if c1.Percentage != c2.Percentage {
+		return false
+	}
+	if c1.DefaultBackend != c2.DefaultBackend {
+		return false
+	}
+	if c1.MirrorBackend != c2.MirrorBackend {
+		return false
+	}
+	if c1.DryRun != c2.DryRun {
+		return false
+	}
+	if !c1.ExpiresAt.Equal(c2.ExpiresAt) {
+		return false
+	}
+
+	if len(c1.Backends) != len(c2.Backends) {
+		return false
+	}
+
+	// weight policies split the requests between the backends sorted by name,
+	// while for the other policies the order decides which backend is selected
+	if c1.Type == PolicyTypeWeight {
+		for _, b1 := range c1.Backends {
+			found := false
+			for _, b2 := range c2.Backends {
+				if b1.Equal(b2) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i := range c1.Backends {
+		if !c1.Backends[i].Equal(c2.Backends[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal tests for equality between two Backend types
+func (b1 *Backend) Equal(b2 *Backend) bool {
+	if b1 == b2 {
+		return true
+	}
+	if b1 == nil || b2 == nil {
+		return false
+	}
+	if b1.Name != b2.Name {
+		return false
+	}
+	if b1.Header != b2.Header {
+		return false
+	}
+	if len(b1.Headers) != len(b2.Headers) {
+		return false
+	}
+	for i := range b1.Headers {
+		if b1.Headers[i] != b2.Headers[i] {
+			return false
+		}
+	}
+	if b1.Weight != b2.Weight {
+		return false
+	}
+	if b1.Port != b2.Port {
+		return false
+	}
+	if b1.Negate != b2.Negate {
+		return false
+	}
+	if len(b1.Methods) != len(b2.Methods) {
+		return false
+	}
+	for i := range b1.Methods {
+		if b1.Methods[i] != b2.Methods[i] {
+			return false
+		}
+	}
+	if len(b1.CIDRs) != len(b2.CIDRs) {
+		return false
+	}
+	for i := range b1.CIDRs {
+		if b1.CIDRs[i] != b2.CIDRs[i] {
+			return false
+		}
+	}
+	if b1.PresenceOnly != b2.PresenceOnly {
+		return false
+	}
+	if b1.Path != b2.Path {
+		return false
+	}
+	if len(b1.SetHeaders) != len(b2.SetHeaders) {
+		return false
+	}
+	for k, v1 := range b1.SetHeaders {
+		v2, ok := b2.SetHeaders[k]
+		if !ok || v1 != v2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns a compact representation of the configuration
+func (c Config) String() string {
+	backends := make([]string, 0, len(c.Backends))
+	for _, b := range c.Backends {
+		if b == nil {
+			backends = append(backends, "<nil>")
+			continue
+		}
+		backends = append(backends, b.String())
+	}
+
+	return fmt.Sprintf("{enabled=%v host=%v paths=%v type=%v header=%v backends=[%v]}",
+		c.Enabled, c.Host, strings.Join(c.PolicyPaths(), ","), c.Type, c.Header, strings.Join(backends, " "))
+}
+
+// String returns a compact representation of the backend
+func (b Backend) String() string {
+	negate := ""
+	if b.Negate {
+		negate = "!"
+	}
+
+	values := strings.Join(b.HeaderValues(), ",")
+	if b.PresenceOnly {
+		values = "*"
+	}
+
+	path := ""
+	if b.Path != "" {
+		path = fmt.Sprintf(" path=%v", b.Path)
+	}
+
+	return fmt.Sprintf("{name=%v headers=%v%v weight=%v port=%v%v}",
+		b.Name, negate, values, b.Weight, b.Port, path)
+}
+
+// DeepCopy returns a copy of the configuration that does not share backends with the original
+func (c *Config) DeepCopy() *Config {
+	if c == nil {
+		return nil
+	}
+
+	out := *c
+	if c.Warnings != nil {
+		out.Warnings = make([]string, len(c.Warnings))
+		copy(out.Warnings, c.Warnings)
+	}
+	if c.Paths != nil {
+		out.Paths = make([]string, len(c.Paths))
+		copy(out.Paths, c.Paths)
+	}
+	if c.ExcludePaths != nil {
+		out.ExcludePaths = make([]string, len(c.ExcludePaths))
+		copy(out.ExcludePaths, c.ExcludePaths)
+	}
+	if c.Backends != nil {
+		out.Backends = make([]*Backend, len(c.Backends))
+		for i, b := range c.Backends {
+			out.Backends[i] = b.DeepCopy()
+		}
+	}
+
+	return &out
+}
+
+// DeepCopy returns a copy of the backend
+func (b *Backend) DeepCopy() *Backend {
+	if b == nil {
+		return nil
+	}
+
+	out := *b
+	if b.Headers != nil {
+		out.Headers = make([]string, len(b.Headers))
+		copy(out.Headers, b.Headers)
+	}
+	if b.Methods != nil {
+		out.Methods = make([]string, len(b.Methods))
+		copy(out.Methods, b.Methods)
+	}
+	if b.CIDRs != nil {
+		out.CIDRs = make([]string, len(b.CIDRs))
+		copy(out.CIDRs, b.CIDRs)
+	}
+	if b.SetHeaders != nil {
+		out.SetHeaders = make(map[string]string, len(b.SetHeaders))
+		for k, v := range b.SetHeaders {
+			out.SetHeaders[k] = v
+		}
+	}
+
+	return &out
+}
+
+// Parse parses the annotations contained in the ingress
+// rule used to indicate if an A/B policy should be enabled and with what config
+func (a abpolicy) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := DefaultConfig()
+	if !hasPolicyAnnotations(ing) {
+		return config, nil
+	}
+
+	var err error
+	config.Enabled, err = parser.GetBoolAnnotation("abpolicy", ing)
+	if err != nil {
+		config.Enabled = false
+	}
+
+	config.DryRun, err = parser.GetBoolAnnotation("abpolicy-dry-run", ing)
+	if err != nil {
+		config.DryRun = false
+	}
+
+	expiresAt, err := parser.GetStringAnnotation("abpolicy-expires-at", ing)
+	if err == nil && expiresAt != "" {
+		config.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, newValidationError(ErrInvalidExpiration, errors.NewInvalidAnnotationContent("abpolicy-expires-at", expiresAt))
+		}
+	}
+
+	config.Host, err = parser.GetStringAnnotation("abpolicy-host", ing)
+	if err != nil {
+		config.Host = ""
+	}
+	// hostnames are case-insensitive
+	config.Host = strings.ToLower(config.Host)
+
+	config.Path, err = parser.GetStringAnnotation("abpolicy-path", ing)
+	if err != nil {
+		config.Path = ""
+	}
+
+	config.Paths, err = parser.GetStringSliceAnnotation("abpolicy-paths", ing)
+	if err != nil || len(config.Paths) == 0 {
+		config.Paths = nil
+	}
+
+	config.ExcludePaths, err = parser.GetStringSliceAnnotation("abpolicy-exclude-paths", ing)
+	if err != nil || len(config.ExcludePaths) == 0 {
+		config.ExcludePaths = nil
+	}
+
+	policyType, err := parser.GetStringAnnotation("abpolicy-type", ing)
+	if err != nil {
+		policyType = ""
+	}
+	config.Type = PolicyType(strings.ToLower(policyType))
+
+	config.Header, err = parser.GetStringAnnotation("abpolicy-header", ing)
+	if err != nil {
+		config.Header = ""
+	}
+
+	config.QueryParam, err = parser.GetStringAnnotation("abpolicy-query-param", ing)
+	if err != nil {
+		config.QueryParam = ""
+	}
+
+	config.Match, err = parser.GetStringAnnotation("abpolicy-match", ing)
+	if err != nil || config.Match == "" {
+		config.Match = MatchExact
+	}
+	config.Match = strings.ToLower(config.Match)
+
+	config.MatchOrder, err = parser.GetStringAnnotation("abpolicy-match-order", ing)
+	if err != nil || config.MatchOrder == "" {
+		config.MatchOrder = MatchOrderFirst
+	}
+	config.MatchOrder = strings.ToLower(config.MatchOrder)
+
+	config.Weight, err = parser.GetFloatAnnotation("abpolicy-weight", ing)
+	if err != nil {
+		if errors.IsInvalidContent(err) {
+			return nil, newValidationError(ErrInvalidWeight, err)
+		}
+		config.Weight = 100
+	}
+
+	config.Sticky, err = parser.GetBoolAnnotation("abpolicy-sticky", ing)
+	if err != nil {
+		config.Sticky = false
+	}
+
+	config.HashBy, err = parser.GetStringAnnotation("abpolicy-hash-by", ing)
+	if err != nil {
+		config.HashBy = ""
+	}
+	config.HashBy = strings.TrimSpace(config.HashBy)
+
+	config.Percentage, err = parser.GetIntAnnotation("abpolicy-percentage", ing)
+	if err != nil {
+		if errors.IsInvalidContent(err) {
+			return nil, newValidationError(ErrInvalidWeight, err)
+		}
+		config.Percentage = 0
+	}
+
+	config.DefaultBackend, err = parser.GetStringAnnotation("abpolicy-default-backend", ing)
+	if err != nil {
+		config.DefaultBackend = ""
+	}
+
+	config.MirrorBackend, err = parser.GetStringAnnotation("abpolicy-mirror-backend", ing)
+	if err != nil {
+		config.MirrorBackend = ""
+	}
+
+	config.MatchSNI, err = parser.GetBoolAnnotation("abpolicy-match-sni", ing)
+	if err != nil {
+		config.MatchSNI = false
+	}
+	if config.MatchSNI && len(ing.Spec.TLS) == 0 {
+		glog.Warningf("Ingress %v/%v enables abpolicy-match-sni without TLS, using the Host header", ing.Namespace, ing.Name)
+		config.MatchSNI = false
+	}
+
+	backends, err := parser.GetStringAnnotation("abpolicy-backends", ing)
+	backends = strings.TrimSpace(backends)
+	if err == nil && backends != "" {
+		// a single backend can be defined as an object instead of a list
+		switch backends[0] {
+		case '[':
+			err = parser.GetJSONAnnotation("abpolicy-backends", ing, &config.Backends)
+		case '{':
+			backend := &Backend{}
+			err = parser.GetJSONAnnotation("abpolicy-backends", ing, backend)
+			config.Backends = []*Backend{backend}
+		default:
+			err = errors.NewInvalidAnnotationContent("abpolicy-backends", backends)
+		}
+		if err != nil {
+			glog.V(2).Infof("invalid abpolicy-backends in Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+			return nil, newValidationError(ErrInvalidBackends, err)
+		}
+	}
+
+	// a null entry of the list decodes to a nil backend, rejected by Validate
+	for _, b := range config.Backends {
+		if b == nil {
+			continue
+		}
+
+		// methods and address ranges are always parsed from the values of the backend
+		if len(b.Methods) > 0 {
+			return nil, newValidationError(ErrInvalidBackends, errors.NewInvalidAnnotationContent("abpolicy-backends", fmt.Sprintf("methods of backend %v cannot be set, use header or headers", b.Name)))
+		}
+		if len(b.CIDRs) > 0 {
+			return nil, newValidationError(ErrInvalidBackends, errors.NewInvalidAnnotationContent("abpolicy-backends", fmt.Sprintf("cidrs of backend %v cannot be set, use header or headers", b.Name)))
+		}
+
+		switch config.Type {
+		case PolicyTypeHeader:
+			// a backend without values matches any request containing the header
+			if len(b.HeaderValues()) == 0 {
+				b.PresenceOnly = true
+			}
+		case PolicyTypeMethod:
+			b.Methods, err = parseMethods(b.HeaderValues())
+			if err != nil {
+				return nil, newValidationError(ErrInvalidBackends, errors.NewInvalidAnnotationContent("abpolicy-backends", err))
+			}
+		case PolicyTypeCIDR:
+			b.CIDRs, err = parseCIDRs(b.HeaderValues())
+			if err != nil {
+				return nil, newValidationError(ErrInvalidBackends, errors.NewInvalidAnnotationContent("abpolicy-backends", err))
+			}
+		}
+	}
+
+	err = config.Validate()
+	if err != nil {
+		kind := ErrInvalidPolicy
+		if ve, ok := err.(ValidationError); ok {
+			kind = ve.Kind
+		}
+		return nil, newValidationError(kind, errors.NewInvalidAnnotationContent("abpolicy", err))
+	}
+
+	skipBackendValidation, err := parser.GetBoolAnnotation("abpolicy-skip-backend-validation", ing)
+	if err != nil {
+		skipBackendValidation = false
+	}
+
+	if !skipBackendValidation {
+		services := ingressServices(ing)
+
+		unknown := []string{}
+		for _, b := range config.Backends {
+			if !services[b.Name] {
+				unknown = append(unknown, b.Name)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, newValidationError(ErrUnknownService, errors.NewInvalidAnnotationContent("abpolicy-backends", fmt.Sprintf("unknown services %v", strings.Join(unknown, ", "))))
+		}
+
+		if config.DefaultBackend != "" && !services[config.DefaultBackend] {
+			return nil, newValidationError(ErrUnknownService, errors.NewInvalidAnnotationContent("abpolicy-default-backend", config.DefaultBackend))
+		}
+
+		if config.MirrorBackend != "" && !services[config.MirrorBackend] {
+			return nil, newValidationError(ErrUnknownService, errors.NewInvalidAnnotationContent("abpolicy-mirror-backend", config.MirrorBackend))
+		}
+	}
+
+	if !config.Enabled && len(config.Backends) == 0 && config.Type != PolicyTypeMirror &&
+		(config.Host != "" || len(config.PolicyPaths()) > 0 || config.Type != "" || config.Header != "") {
+		msg := "policy is disabled and has no backends, it cannot be enabled without backends"
+		glog.V(2).Infof("abpolicy in Ingress %v/%v: %v", ing.Namespace, ing.Name, msg)
+		config.Warnings = append(config.Warnings, msg)
+	}
+
+	if unreachable := config.UnreachableBackends(); len(unreachable) > 0 {
+		msg := fmt.Sprintf("backends %v are shadowed by previous backends and never receive traffic", strings.Join(unreachable, ", "))
+		glog.V(2).Infof("abpolicy in Ingress %v/%v: %v", ing.Namespace, ing.Name, msg)
+		config.Warnings = append(config.Warnings, msg)
+	}
+
+	if glog.V(3) {
+		glog.Infof("abpolicy in Ingress %v/%v has fingerprint %v", ing.Namespace, ing.Name, config.Fingerprint())
+	}
+
+	return config, nil
+}
+
+// IsExcluded returns true when the path of a request is prefixed
+// by one of the excluded paths, so the policy must not be applied
+func (c *Config) IsExcluded(path string) bool {
+	for _, p := range c.ExcludePaths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fingerprint returns the SHA-256 hash of a canonical JSON encoding of the policy,
+// which identifies its configuration across reconciliations. The backends of weight
+// policies are sorted by name, as their order does not change the split, while the
+// other policies keep the declared order, which decides the backend selected for a
+// request. Warnings are ignored.
+func (c *Config) Fingerprint() string {
+	canonical := c.DeepCopy()
+	canonical.Warnings = nil
+	canonical.Match = c.matchMode()
+	canonical.MatchOrder = c.matchOrder()
+	canonical.ExpiresAt = c.ExpiresAt.UTC()
+
+	canonical.Backends = make([]*Backend, 0, len(c.Backends))
+	for _, b := range c.Backends {
+		if b != nil {
+			canonical.Backends = append(canonical.Backends, b.DeepCopy())
+		}
+	}
+	if c.Type == PolicyTypeWeight {
+		sort.SliceStable(canonical.Backends, func(i, j int) bool {
+			return canonical.Backends[i].Name < canonical.Backends[j].Name
+		})
+	}
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		glog.Errorf("unexpected error encoding abpolicy %v: %v", c, err)
+		return ""
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// IsEnabled returns true when the policy is enabled and not expired at the given time.
+// It must be used instead of Enabled to decide if the policy is applied.
+func (c *Config) IsEnabled(now time.Time) bool {
+	if !c.Enabled {
+		return false
+	}
+
+	return c.ExpiresAt.IsZero() || now.Before(c.ExpiresAt)
+}
+
+// PolicyPaths returns the paths the policy is applied to
+func (c *Config) PolicyPaths() []string {
+	if len(c.Paths) > 0 {
+		return c.Paths
+	}
+	if c.Path != "" {
+		return []string{c.Path}
+	}
+
+	return []string{}
+}
+
+// matchMode returns the match mode of the policy, using MatchExact when it is not set
+func (c *Config) matchMode() string {
+	if c.Match == "" {
+		return MatchExact
+	}
+
+	return c.Match
+}
+
+// matchOrder returns the match order of the policy, using MatchOrderFirst when it is not set
+func (c *Config) matchOrder() string {
+	if c.MatchOrder == "" {
+		return MatchOrderFirst
+	}
+
+	return c.MatchOrder
+}
+
+// UnreachableBackends returns the names of the backends that can never be selected
+// because, under the match order of the policy, the backends evaluated before them
+// already match all their values and paths. Negated backends are never reported.
+func (c *Config) UnreachableBackends() []string {
+	if !matchesValues(c.Type) {
+		return nil
+	}
+
+	ordered := make([]*Backend, 0, len(c.Backends))
+	for i := range c.Backends {
+		b := c.Backends[i]
+		if c.matchOrder() == MatchOrderLast {
+			b = c.Backends[len(c.Backends)-1-i]
+		}
+		if b != nil {
+			ordered = append(ordered, b)
+		}
+	}
+
+	unreachable := []string{}
+	for i, b := range ordered {
+		if b.Negate {
+			continue
+		}
+
+		covered := map[string]bool{}
+		shadowed := false
+		for _, prev := range ordered[:i] {
+			if prev.Negate || !prev.MatchesPath(b.Path) {
+				continue
+			}
+			if prev.PresenceOnly {
+				shadowed = true
+				break
+			}
+			for _, v := range prev.matchValues() {
+				covered[v] = true
+			}
+		}
+
+		if !shadowed && !b.PresenceOnly {
+			values := b.matchValues()
+			shadowed = len(values) > 0
+			for _, v := range values {
+				if !covered[v] {
+					shadowed = false
+					break
+				}
+			}
+		}
+
+		if shadowed {
+			unreachable = append(unreachable, b.Name)
+		}
+	}
+
+	return unreachable
+}
+
+// matchValues returns the values compared with the request to select the backend
+func (b *Backend) matchValues() []string {
+	switch {
+	case len(b.Methods) > 0:
+		return b.Methods
+	case len(b.CIDRs) > 0:
+		return b.CIDRs
+	default:
+		return b.HeaderValues()
+	}
+}
+
+// weightRangeSize is the number of buckets split between the backends of a weight policy
+const weightRangeSize = 100
+
+// WeightRange is the inclusive range of buckets, between 0 and 99, assigned to a backend
+type WeightRange struct {
+	Backend string
+	Start   int
+	End     int
+}
+
+// WeightRanges splits the buckets 0-99 between the backends of a weight policy in
+// contiguous ranges proportional to their weights. Every backend with a positive
+// weight gets at least one bucket, taken from the largest ranges when needed, and
+// the buckets lost when rounding are assigned to the last backend receiving traffic,
+// so every bucket selects a backend. Backends with a zero weight do not get a range.
+func (c *Config) WeightRanges() ([]WeightRange, error) {
+	if c.Type != PolicyTypeWeight {
+		return nil, errors.Errorf("weight ranges are not supported by %v policies", c.Type)
+	}
+
+	total := 0
+	positive := 0
+	for i, b := range c.Backends {
+		if b == nil {
+			return nil, errors.Errorf("backend %v is nil", i)
+		}
+		if b.Weight < 0 {
+			return nil, errors.Errorf("backend %v has a negative weight %v", b.Name, b.Weight)
+		}
+		if b.Weight > 0 {
+			positive++
+		}
+		total += b.Weight
+	}
+
+	if total == 0 {
+		return nil, errors.New("weight policy without backends receiving traffic")
+	}
+	if positive > weightRangeSize {
+		return nil, errors.Errorf("weight policy with more than %v backends receiving traffic", weightRangeSize)
+	}
+
+	sizes := make([]int, len(c.Backends))
+	used := 0
+	last := -1
+	for i, b := range c.Backends {
+		if b.Weight == 0 {
+			continue
+		}
+
+		sizes[i] = b.Weight * weightRangeSize / total
+		if sizes[i] == 0 {
+			sizes[i] = 1
+		}
+		used += sizes[i]
+		last = i
+	}
+
+	// the minimum bucket of the smallest backends can exceed the available buckets
+	for used > weightRangeSize {
+		largest := 0
+		for i := range sizes {
+			if sizes[i] > sizes[largest] {
+				largest = i
+			}
+		}
+		sizes[largest]--
+		used--
+	}
+	sizes[last] += weightRangeSize - used
+
+	ranges := []WeightRange{}
+	start := 0
+	for i, b := range c.Backends {
+		if sizes[i] == 0 {
+			continue
+		}
+
+		ranges = append(ranges, WeightRange{Backend: b.Name, Start: start, End: start + sizes[i] - 1})
+		start += sizes[i]
+	}
+
+	return ranges, nil
+}
+
+// SortedWeightRanges returns the weight ranges of the backends sorted by name. Unlike
+// WeightRanges, the result is a pure function of the set of backends and their weights,
+// so every replica of the controller builds the same ranges regardless of the order
+// used to define the backends.
+func (c *Config) SortedWeightRanges() ([]WeightRange, error) {
+	sorted := &Config{
+		Type:     c.Type,
+		Backends: make([]*Backend, len(c.Backends)),
+	}
+	for i, b := range c.Backends {
+		if b == nil {
+			return nil, errors.Errorf("backend %v is nil", i)
+		}
+		sorted.Backends[i] = b
+	}
+	sort.SliceStable(sorted.Backends, func(i, j int) bool {
+		return sorted.Backends[i].Name < sorted.Backends[j].Name
+	})
+
+	return sorted.WeightRanges()
+}
+
+// Validate checks the values of the configuration and that it contains
+// the fields required by an enabled policy
+func (c *Config) Validate() error {
+	if c.Host != "" && !isValidHost(c.Host) {
+		return newValidationError(ErrInvalidHost, errors.Errorf("host %v is not a valid DNS name", c.Host))
+	}
+
+	if c.Path != "" && len(c.Paths) > 0 {
+		return newValidationError(ErrInvalidPath, errors.New("path and paths cannot be used together"))
+	}
+
+	if c.Enabled {
+		for _, p := range c.PolicyPaths() {
+			if !strings.HasPrefix(p, "/") {
+				return newValidationError(ErrInvalidPath, errors.Errorf("path %v is not absolute", p))
+			}
+		}
+	}
+
+	for _, p := range c.ExcludePaths {
+		if !strings.HasPrefix(p, "/") {
+			return newValidationError(ErrInvalidPath, errors.Errorf("excluded path %v is not absolute", p))
+		}
+	}
+
+	if c.Type != "" && !isSupportedType(string(c.Type)) {
+		return newValidationError(ErrInvalidType, errors.Errorf("type %v is not supported", c.Type))
+	}
+
+	if c.Header != "" && !isValidHeaderName(c.Header) {
+		return newValidationError(ErrInvalidHeader, errors.Errorf("header %q is not a valid header name", c.Header))
+	}
+
+	if c.Match != "" && c.Match != MatchExact && c.Match != MatchRegex {
+		return newValidationError(ErrInvalidMatch, errors.Errorf("match %v is not supported", c.Match))
+	}
+
+	if c.MatchOrder != "" && c.MatchOrder != MatchOrderFirst && c.MatchOrder != MatchOrderLast {
+		return newValidationError(ErrInvalidMatch, errors.Errorf("match order %v is not supported", c.MatchOrder))
+	}
+
+	if c.Weight < 0 || c.Weight > 100 {
+		return newValidationError(ErrInvalidWeight, errors.Errorf("weight %v is not a percentage", c.Weight))
+	}
+
+	if c.Percentage < 0 || c.Percentage > 100 {
+		return newValidationError(ErrInvalidWeight, errors.Errorf("percentage %v is not between 0 and 100", c.Percentage))
+	}
+
+	if c.Sticky && c.Type != PolicyTypeWeight {
+		return newValidationError(ErrInvalidPolicy, errors.Errorf("sticky is not supported by %v policies", c.Type))
+	}
+
+	if c.Sticky && c.HashBy == "" {
+		return newValidationError(ErrInvalidPolicy, errors.New("sticky policy without hash key"))
+	}
+
+	if c.HashBy != "" && !isValidHashBy(c.HashBy) {
+		return newValidationError(ErrInvalidHeader, errors.Errorf("hash key %q is not remote_addr, cookie or a header name usable in an NGINX variable", c.HashBy))
+	}
+
+	if c.Type == PolicyTypeQuery && c.QueryParam == "" {
+		return newValidationError(ErrInvalidPolicy, errors.New("query policy without query parameter"))
+	}
+
+	if c.DefaultBackend != "" {
+		if errs := validation.IsDNS1035Label(c.DefaultBackend); len(errs) > 0 {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("default backend %v is not a valid service name: %v", c.DefaultBackend, strings.Join(errs, ", ")))
+		}
+	}
+
+	if c.MirrorBackend != "" && c.Type != PolicyTypeMirror {
+		return newValidationError(ErrInvalidBackends, errors.Errorf("mirror backend is not supported by %v policies", c.Type))
+	}
+
+	if c.Type == PolicyTypeMirror && len(c.Backends) > 0 {
+		return newValidationError(ErrInvalidBackends, errors.New("backends are not supported by mirror policies"))
+	}
+
+	names := map[string]bool{}
+	for i, b := range c.Backends {
+		if b == nil {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("backend %v is nil", i))
+		}
+
+		if names[b.Name] {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("duplicated backend %v", b.Name))
+		}
+		names[b.Name] = true
+
+		if b.Port < 0 || b.Port > 65535 {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("invalid port %v in backend %v", b.Port, b.Name))
+		}
+
+		if b.Path != "" && !strings.HasPrefix(b.Path, "/") {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("path %v of backend %v is not absolute", b.Path, b.Name))
+		}
+
+		for h := range b.SetHeaders {
+			if !isValidHeaderName(h) {
+				return newValidationError(ErrInvalidHeader, errors.Errorf("invalid header name %q in setHeaders of backend %v", h, b.Name))
+			}
+		}
+
+		if b.Negate && !matchesValues(c.Type) {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("negate is not supported by %v policies", c.Type))
+		}
+
+		if b.PresenceOnly && c.Type != PolicyTypeHeader {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("presenceOnly is not supported by %v policies", c.Type))
+		}
+
+		if b.PresenceOnly && len(b.HeaderValues()) > 0 {
+			return newValidationError(ErrInvalidBackends, errors.Errorf("backend %v cannot define values and presenceOnly", b.Name))
+		}
+
+		if len(b.Methods) > 0 {
+			if c.Type != PolicyTypeMethod {
+				return newValidationError(ErrInvalidBackends, errors.Errorf("methods of backend %v are not supported by %v policies", b.Name, c.Type))
+			}
+			for _, m := range b.Methods {
+				if !isSupportedMethod(m) {
+					return newValidationError(ErrInvalidBackends, errors.Errorf("unknown HTTP method %v in backend %v", m, b.Name))
+				}
+			}
+		}
+
+		if len(b.CIDRs) > 0 {
+			if c.Type != PolicyTypeCIDR {
+				return newValidationError(ErrInvalidBackends, errors.Errorf("cidrs of backend %v are not supported by %v policies", b.Name, c.Type))
+			}
+			if _, err := parseCIDRs(b.CIDRs); err != nil {
+				return newValidationError(ErrInvalidBackends, errors.Errorf("backend %v: %v", b.Name, err))
+			}
+		}
+
+		if c.Match == MatchRegex {
+			for _, v := range b.HeaderValues() {
+				if _, err := regexp.Compile(v); err != nil {
+					return newValidationError(ErrInvalidBackends, errors.Errorf("backend %v: %v", b.Name, err))
+				}
+			}
+		}
+	}
+
+	if c.Type == PolicyTypeWeight && len(c.Backends) > 0 && !validWeights(c.Backends) {
+		return newValidationError(ErrInvalidBackends, errors.New("weight policy without backends receiving traffic"))
+	}
+
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Type == PolicyTypeMirror && c.MirrorBackend == "" {
+		return newValidationError(ErrEmptyBackends, errors.New("mirror policy without mirror backend"))
+	}
+
+	if len(c.Backends) == 0 && c.Type != PolicyTypeMirror {
+		return newValidationError(ErrEmptyBackends, errors.New("enabled policy without backends"))
+	}
+
+	if c.Host == "" {
+		return newValidationError(ErrInvalidHost, errors.New("enabled policy without host"))
+	}
+
+	if c.Type == "" {
+		return newValidationError(ErrInvalidType, errors.New("enabled policy without type"))
+	}
+
+	if len(c.PolicyPaths()) == 0 {
+		return newValidationError(ErrInvalidPath, errors.New("enabled policy without path"))
+	}
+
+	if c.Type == PolicyTypePercentage && len(c.Backends) != 2 {
+		return newValidationError(ErrInvalidBackends, errors.Errorf("percentage policy requires two backends but %v are defined", len(c.Backends)))
+	}
+
+	return nil
+}
+
+// hasPolicyAnnotations returns true when the ingress contains any abpolicy annotation
+func hasPolicyAnnotations(ing *extensions.Ingress) bool {
+	name := parser.GetAnnotationWithPrefix("abpolicy")
+	for k := range ing.GetAnnotations() {
+		if k == name || strings.HasPrefix(k, name+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ingressServices returns the names of the services referenced by the ingress
+func ingressServices(ing *extensions.Ingress) map[string]bool {
+	services := map[string]bool{}
+	if ing.Spec.Backend != nil {
+		services[ing.Spec.Backend.ServiceName] = true
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			services[path.Backend.ServiceName] = true
+		}
+	}
+
+	return services
+}
+
+// isValidHost checks the host is a DNS-1123 subdomain,
+// optionally prefixed with *. to match any subdomain
+func isValidHost(host string) bool {
+	host = strings.TrimPrefix(host, "*.")
+	return len(validation.IsDNS1123Subdomain(host)) == 0
+}
+
+// isValidHeaderName checks the name is a token as defined by RFC 7230,
+// so it can be used in the NGINX configuration without escaping
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidHashBy checks the key is remote_addr, cookie or a header name
+// whose NGINX variable, http_ followed by the lower-cased name with dashes
+// replaced by underscores, only contains letters, digits and underscores
+func isValidHashBy(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseMethods returns the upper-cased HTTP methods contained
+// in a list of comma-separated values
+func parseMethods(values []string) ([]string, error) {
+	methods := []string{}
+	for _, v := range values {
+		for _, m := range strings.Split(v, ",") {
+			m = strings.ToUpper(strings.TrimSpace(m))
+			if m == "" {
+				continue
+			}
+			if !isSupportedMethod(m) {
+				return nil, errors.Errorf("unknown HTTP method %v", m)
+			}
+			methods = append(methods, m)
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("backend without HTTP methods")
+	}
+
+	return methods, nil
+}
+
+// parseCIDRs returns the address ranges contained
+// in a list of comma-separated values
+func parseCIDRs(values []string) ([]string, error) {
+	cidrs := []string{}
+	for _, v := range values {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.TrimSpace(c)
+			if c == "" {
+				continue
+			}
+			_, _, err := net.ParseCIDR(c)
+			if err != nil {
+				return nil, errors.Errorf("invalid CIDR %v", c)
+			}
+			cidrs = append(cidrs, c)
+		}
+	}
+
+	if len(cidrs) == 0 {
+		return nil, errors.New("backend without CIDRs")
+	}
+
+	return cidrs, nil
+}
+
+func isSupportedMethod(m string) bool {
+	for _, sm := range supportedMethods {
+		if m == sm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesValues returns true when the policy selects
+// backends comparing the request with their values
+func matchesValues(t PolicyType) bool {
+	switch t {
+	case PolicyTypeHeader, PolicyTypeCookie, PolicyTypeQuery, PolicyTypeMethod, PolicyTypeCIDR:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSupportedType(t string) bool {
+	for _, st := range SupportedTypes {
+		if t == st {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validWeights checks the backends are not nil, their weights are not
+// negative and that at least one of them receives traffic
+func validWeights(backends []*Backend) bool {
+	total := 0
+	for _, b := range backends {
+		if b == nil || b.Weight < 0 {
+			return false
+		}
+		total += b.Weight
+	}
+
+	return total > 0
+}
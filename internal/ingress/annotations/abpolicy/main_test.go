@@ -0,0 +1,2266 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package abpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func buildIngress() *extensions.Ingress {
+	defaultBackend := extensions.IngressBackend{
+		ServiceName: "default-backend",
+		ServicePort: intstr.FromInt(80),
+	}
+
+	return &extensions.Ingress{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-backend",
+				ServicePort: intstr.FromInt(80),
+			},
+			Rules: []extensions.IngressRule{
+				{
+					Host: "foo.bar.com",
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{
+								{
+									Path:    "/foo",
+									Backend: defaultBackend,
+								},
+								{
+									Path:    "/foo",
+									Backend: extensions.IngressBackend{ServiceName: "svc-a", ServicePort: intstr.FromInt(80)},
+								},
+								{
+									Path:    "/foo",
+									Backend: extensions.IngressBackend{ServiceName: "svc-b", ServicePort: intstr.FromInt(80)},
+								},
+								{
+									Path:    "/foo",
+									Backend: extensions.IngressBackend{ServiceName: "svc-c", ServicePort: intstr.FromInt(80)},
+								},
+								{
+									Path:    "/foo",
+									Backend: extensions.IngressBackend{ServiceName: "svc-d", ServicePort: intstr.FromInt(80)},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		enabled  bool
+		host     string
+		path     string
+		abType   string
+		backends string
+		expType  PolicyType
+		expErr   bool
+	}{
+		{"policy disabled", false, "", "", "", "", "", false},
+		{"policy enabled by header", true, "foo.bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "header", false},
+		{"policy enabled by weight", true, "foo.bar.com", "/foo", "weight", `[{"name":"svc-a","weight":90},{"name":"svc-b","weight":10}]`, "weight", false},
+		{"policy enabled by weight with zero total", true, "foo.bar.com", "/foo", "weight", `[{"name":"svc-a"},{"name":"svc-b","weight":0}]`, "", true},
+		{"policy enabled by weight with negative weight", true, "foo.bar.com", "/foo", "weight", `[{"name":"svc-a","weight":110},{"name":"svc-b","weight":-10}]`, "", true},
+		{"policy by header ignores weights", true, "foo.bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2","weight":-1}]`, "header", false},
+		{"policy enabled by cookie", true, "foo.bar.com", "/foo", "cookie", `[{"name":"svc-b","header":"v2"}]`, "cookie", false},
+		{"policy host is normalized", true, "Foo.Bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "header", false},
+		{"policy with wildcard host", true, "*.bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "header", false},
+		{"policy with spaces in host", true, "foo bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy with misplaced wildcard", true, "foo.*.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy with wildcard only", true, "*", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy with double wildcard", true, "*.*.bar.com", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy type is normalized", true, "foo.bar.com", "/foo", "Header", `[{"name":"svc-b","header":"v2"}]`, "header", false},
+		{"policy with unknown type", true, "foo.bar.com", "/foo", "headr", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy disabled with unknown type", false, "", "", "headr", "", "", true},
+		{"policy disabled with malformed backends", false, "", "", "", `[{"name":"svc-b"`, "", true},
+		{"policy enabled with duplicated backends", true, "foo.bar.com", "/foo", "header", `[{"name":"svc-b","header":"v1"},{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy disabled with duplicated backends", false, "", "", "", `[{"name":"svc-b","header":"v1"},{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy enabled with malformed backends", true, "foo.bar.com", "/foo", "header", `[{"name":"svc-b"`, "", true},
+		{"policy enabled without backends", true, "foo.bar.com", "/foo", "header", "", "", true},
+		{"policy enabled without host", true, "", "/foo", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy enabled without type", true, "foo.bar.com", "/foo", "", `[{"name":"svc-b","header":"v2"}]`, "", true},
+		{"policy enabled without path", true, "foo.bar.com", "", "header", `[{"name":"svc-b","header":"v2"}]`, "", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy")] = strconv.FormatBool(test.enabled)
+		data[parser.GetAnnotationWithPrefix("abpolicy-host")] = test.host
+		data[parser.GetAnnotationWithPrefix("abpolicy-path")] = test.path
+		data[parser.GetAnnotationWithPrefix("abpolicy-type")] = test.abType
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+
+			continue
+		} else {
+			if err != nil {
+				t.Errorf("%v: expected nil but returned error %v", test.title, err)
+				continue
+			}
+		}
+
+		abConfig, ok := i.(*Config)
+		if !ok {
+			t.Errorf("%v: expected a Config type", test.title)
+			continue
+		}
+		if abConfig.Enabled != test.enabled {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.enabled, abConfig.Enabled)
+		}
+		if abConfig.Host != strings.ToLower(test.host) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, strings.ToLower(test.host), abConfig.Host)
+		}
+		if abConfig.Type != test.expType {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expType, abConfig.Type)
+		}
+	}
+}
+
+func TestBackendWeights(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "weight"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","weight":90},{"name":"svc-b","weight":10}]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	abConfig := i.(*Config)
+	if len(abConfig.Backends) != 2 {
+		t.Fatalf("expected 2 backends but %v returned", len(abConfig.Backends))
+	}
+	if abConfig.Backends[0].Weight != 90 || abConfig.Backends[1].Weight != 10 {
+		t.Errorf("expected weights 90/10 but %v/%v returned", abConfig.Backends[0].Weight, abConfig.Backends[1].Weight)
+	}
+}
+
+func TestBackendsShape(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		backends string
+		expNames []string
+		expErr   bool
+	}{
+		{"list of backends", `[{"name":"svc-a","header":"v1"},{"name":"svc-b","header":"v2"}]`, []string{"svc-a", "svc-b"}, false},
+		{"single backend", `{"name":"svc-b","header":"v2"}`, []string{"svc-b"}, false},
+		{"single backend with spaces", ` {"name":"svc-b","header":"v2"} `, []string{"svc-b"}, false},
+		{"not a JSON object or list", `svc-b`, nil, true},
+		{"JSON string", `"svc-b"`, nil, true},
+		{"malformed list", `[{"name":"svc-b","header":"v2"}`, nil, true},
+		{"malformed object", `{"name":"svc-b",}`, nil, true},
+		{"wrong field type", `[{"name":"svc-b","weight":"10"}]`, nil, true},
+		{"null backend", `[null]`, nil, true},
+		{"null backend after a valid one", `[{"name":"svc-b","header":"v2"},null]`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if len(abConfig.Backends) != len(test.expNames) {
+			t.Errorf("%v: expected %v backends but %v returned", test.title, len(test.expNames), len(abConfig.Backends))
+			continue
+		}
+		for idx, name := range test.expNames {
+			if abConfig.Backends[idx].Name != name {
+				t.Errorf("%v: expected backend \"%v\" but \"%v\" returned", test.title, name, abConfig.Backends[idx].Name)
+			}
+		}
+	}
+}
+
+// errorKind returns the kind of a ValidationError, or nil if err is not one
+func errorKind(err error) error {
+	verr, ok := err.(ValidationError)
+	if !ok {
+		return nil
+	}
+	return verr.Kind
+}
+
+func TestMalformedBackendsError(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","header":"v1"`
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err == nil {
+		t.Fatalf("expected error for malformed abpolicy-backends but returned nil")
+	}
+	if !errors.IsInvalidContent(err) {
+		t.Errorf("expected an invalid content error but %v was returned", err)
+	}
+	if !strings.Contains(err.Error(), parser.GetAnnotationWithPrefix("abpolicy-backends")) {
+		t.Errorf("expected error to reference the abpolicy-backends annotation but \"%v\" was returned", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	backends := []*Backend{{Name: "svc-b", Header: "v2"}}
+	weightBackends := []*Backend{{Name: "svc-b", Weight: 100}}
+
+	tests := []struct {
+		title  string
+		config *Config
+		expErr bool
+	}{
+		{"disabled policy", &Config{}, false},
+		{"disabled policy with unsupported type", &Config{Type: "headr"}, true},
+		{"sticky weight policy", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: PolicyTypeWeight, Sticky: true, HashBy: "remote_addr", Backends: weightBackends}, false},
+		{"sticky weight policy without hash key", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: PolicyTypeWeight, Sticky: true, Backends: weightBackends}, true},
+		{"sticky header policy", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: PolicyTypeHeader, Sticky: true, HashBy: "remote_addr", Backends: backends}, true},
+		{"query policy", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: PolicyTypeQuery, QueryParam: "exp", Backends: backends}, false},
+		{"query policy without query parameter", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: PolicyTypeQuery, Backends: backends}, true},
+		{"enabled policy", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: "header", Backends: backends}, false},
+		{"enabled policy without backends", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Type: "header"}, true},
+		{"enabled policy without host", &Config{Enabled: true, Path: "/foo", Type: "header", Backends: backends}, true},
+		{"enabled policy without type", &Config{Enabled: true, Host: "foo.bar.com", Path: "/foo", Backends: backends}, true},
+		{"enabled policy without path", &Config{Enabled: true, Host: "foo.bar.com", Type: "header", Backends: backends}, true},
+		{"invalid host", &Config{Host: "foo_bar.com"}, true},
+		{"path and paths", &Config{Path: "/foo", Paths: []string{"/bar"}}, true},
+		{"relative path", &Config{Enabled: true, Host: "foo.bar.com", Path: "foo", Type: "header", Backends: backends}, true},
+		{"invalid header", &Config{Header: "X Variant"}, true},
+		{"unsupported match", &Config{Match: "glob"}, true},
+		{"nil backend", &Config{Type: "header", Backends: []*Backend{nil}}, true},
+		{"duplicated backend", &Config{Type: "header", Backends: []*Backend{{Name: "svc-b", Header: "v1"}, {Name: "svc-b", Header: "v2"}}}, true},
+		{"invalid regex", &Config{Type: "header", Match: MatchRegex, Backends: []*Backend{{Name: "svc-b", Header: "("}}}, true},
+		{"weight policy without weights", &Config{Type: PolicyTypeWeight, Backends: []*Backend{{Name: "svc-b"}}}, true},
+	}
+
+	for _, test := range tests {
+		err := test.config.Validate()
+		if test.expErr && err == nil {
+			t.Errorf("%v: expected error but returned nil", test.title)
+		}
+		if !test.expErr && err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	c1 := &Config{
+		Enabled: true,
+		Host:    "foo.bar.com",
+		Path:    "/foo",
+		Type:    "weight",
+		Backends: []*Backend{
+			{Name: "svc-a", Weight: 90},
+			{Name: "svc-b", Weight: 10},
+		},
+	}
+	c2 := &Config{
+		Enabled: true,
+		Host:    "foo.bar.com",
+		Path:    "/foo",
+		Type:    "weight",
+		Backends: []*Backend{
+			{Name: "svc-b", Weight: 10},
+			{Name: "svc-a", Weight: 90},
+		},
+	}
+
+	if !c1.Equal(c2) {
+		t.Errorf("expected configurations with reordered backends to be equal")
+	}
+
+	c2.Backends[0].Weight = 20
+	if c1.Equal(c2) {
+		t.Errorf("expected configurations with different weights to be different")
+	}
+
+	c2.Backends = nil
+	if c1.Equal(c2) {
+		t.Errorf("expected configurations with and without backends to be different")
+	}
+
+	var nilConfig *Config
+	if nilConfig.Equal(c1) || c1.Equal(nilConfig) {
+		t.Errorf("expected nil configuration to be different")
+	}
+	if !nilConfig.Equal(nil) {
+		t.Errorf("expected nil configurations to be equal")
+	}
+	if !(&Config{}).Equal(&Config{Backends: []*Backend{}}) {
+		t.Errorf("expected nil and empty backends to be equal")
+	}
+
+	h1 := &Config{Type: PolicyTypeHeader, Backends: []*Backend{{Name: "svc-a", Header: "v1"}, {Name: "svc-b", Header: "v1"}}}
+	h2 := &Config{Type: PolicyTypeHeader, Backends: []*Backend{{Name: "svc-b", Header: "v1"}, {Name: "svc-a", Header: "v1"}}}
+	if h1.Equal(h2) {
+		t.Errorf("expected header policies with reordered backends to be different")
+	}
+}
+
+func TestHeaderValues(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[
+		{"name":"svc-a","header":"v1"},
+		{"name":"svc-b","headers":["v2","beta"]},
+		{"name":"svc-c","header":"v3","headers":["v4"]},
+		{"name":"svc-d"}
+	]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]string{{"v1"}, {"v2", "beta"}, {"v4"}, {}}
+
+	abConfig := i.(*Config)
+	for idx, backend := range abConfig.Backends {
+		values := backend.HeaderValues()
+		if strings.Join(values, ",") != strings.Join(expected[idx], ",") {
+			t.Errorf("backend %v: expected %v but %v returned", backend.Name, expected[idx], values)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "User-Agent"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		match    string
+		backends string
+		expMatch string
+		expErr   bool
+	}{
+		{"default match", "", `[{"name":"svc-b","header":"(Android"}]`, MatchExact, false},
+		{"exact match", "exact", `[{"name":"svc-b","header":"(Android"}]`, MatchExact, false},
+		{"regex match", "regex", `[{"name":"svc-b","headers":["(Android|iPhone)", "Mobile$"]}]`, MatchRegex, false},
+		{"regex match is normalized", "Regex", `[{"name":"svc-b","header":"Android"}]`, MatchRegex, false},
+		{"invalid regex", "regex", `[{"name":"svc-b","header":"(Android"}]`, "", true},
+		{"invalid regex in list", "regex", `[{"name":"svc-b","headers":["Android", "(iPhone"]}]`, "", true},
+		{"unknown match", "prefix", `[{"name":"svc-b","header":"Android"}]`, "", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-match")] = test.match
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.Match != test.expMatch {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expMatch, abConfig.Match)
+		}
+	}
+}
+
+func TestBackendServices(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		backends string
+		skip     string
+		expErr   bool
+	}{
+		{"services of the ingress", `[{"name":"svc-a","header":"v1"},{"name":"svc-b","header":"v2"}]`, "", false},
+		{"default backend of the ingress", `[{"name":"default-backend","header":"v1"}]`, "", false},
+		{"unknown service", `[{"name":"svc-a","header":"v1"},{"name":"svc-x","header":"v2"}]`, "", true},
+		{"unknown service without validation", `[{"name":"svc-a","header":"v1"},{"name":"svc-x","header":"v2"}]`, "true", false},
+		{"unknown service with validation", `[{"name":"svc-x","header":"v2"}]`, "false", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+		data[parser.GetAnnotationWithPrefix("abpolicy-skip-backend-validation")] = test.skip
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr && err == nil {
+			t.Errorf("%v: expected error but returned nil", test.title)
+		}
+		if !test.expErr && err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+		}
+	}
+}
+
+func TestBackendPort(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		backends string
+		expPort  int
+		expErr   bool
+	}{
+		{"default port", `[{"name":"svc-b","header":"v2"}]`, 0, false},
+		{"custom port", `[{"name":"svc-b","header":"v2","port":8080}]`, 8080, false},
+		{"highest port", `[{"name":"svc-b","header":"v2","port":65535}]`, 65535, false},
+		{"negative port", `[{"name":"svc-b","header":"v2","port":-1}]`, 0, true},
+		{"port out of range", `[{"name":"svc-b","header":"v2","port":65536}]`, 0, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.Backends[0].Port != test.expPort {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expPort, abConfig.Backends[0].Port)
+		}
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	c1 := &Config{
+		Enabled: true,
+		Host:    "foo.bar.com",
+		Path:    "/foo",
+		Type:    "header",
+		Backends: []*Backend{
+			{Name: "svc-a", Header: "v1"},
+			{Name: "svc-b", Headers: []string{"v2", "beta"}},
+		},
+	}
+
+	c2 := c1.DeepCopy()
+	if !c1.Equal(c2) {
+		t.Fatalf("expected copy to be equal to the original")
+	}
+
+	c2.Host = "bar.foo.com"
+	c2.Backends[0].Name = "svc-c"
+	c2.Backends[1].Headers[0] = "v3"
+	c2.Backends = append(c2.Backends, &Backend{Name: "svc-d"})
+
+	if c1.Host != "foo.bar.com" {
+		t.Errorf("expected original host to be unchanged but %v returned", c1.Host)
+	}
+	if len(c1.Backends) != 2 {
+		t.Errorf("expected original backends to be unchanged but %v returned", len(c1.Backends))
+	}
+	if c1.Backends[0].Name != "svc-a" {
+		t.Errorf("expected original backend name to be unchanged but %v returned", c1.Backends[0].Name)
+	}
+	if c1.Backends[1].Headers[0] != "v2" {
+		t.Errorf("expected original backend headers to be unchanged but %v returned", c1.Backends[1].Headers)
+	}
+
+	var nilConfig *Config
+	if nilConfig.DeepCopy() != nil {
+		t.Errorf("expected copy of nil configuration to be nil")
+	}
+}
+
+func TestMissingAnnotationsKeepOtherFields(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	abConfig := i.(*Config)
+	if abConfig.Host != "foo.bar.com" {
+		t.Errorf("expected host to survive a missing abpolicy-header but %v returned", abConfig.Host)
+	}
+	if abConfig.Path != "/foo" {
+		t.Errorf("expected path to survive a missing abpolicy-type but %v returned", abConfig.Path)
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "x-variant"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	abConfig = i.(*Config)
+	if abConfig.Host != "foo.bar.com" {
+		t.Errorf("expected host to survive a missing abpolicy-type but %v returned", abConfig.Host)
+	}
+	if abConfig.Header != "x-variant" {
+		t.Errorf("expected header to survive a missing abpolicy-type but %v returned", abConfig.Header)
+	}
+}
+
+func TestDefaultBackend(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title          string
+		defaultBackend string
+		skip           string
+		expErr         bool
+	}{
+		{"without default backend", "", "", false},
+		{"default backend of the ingress", "svc-a", "", false},
+		{"unknown default backend", "svc-x", "", true},
+		{"unknown default backend without validation", "svc-x", "true", false},
+		{"invalid default backend name", "Svc_X", "true", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-default-backend")] = test.defaultBackend
+		data[parser.GetAnnotationWithPrefix("abpolicy-skip-backend-validation")] = test.skip
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.DefaultBackend != test.defaultBackend {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.defaultBackend, abConfig.DefaultBackend)
+		}
+	}
+
+	c1 := &Config{DefaultBackend: "svc-a"}
+	c2 := &Config{DefaultBackend: "svc-b"}
+	if c1.Equal(c2) {
+		t.Errorf("expected configurations with different default backends to be different")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		path     string
+		paths    string
+		expPaths []string
+		expErr   bool
+	}{
+		{"single path", "/foo", "", []string{"/foo"}, false},
+		{"several paths", "", "/foo, /bar,,", []string{"/foo", "/bar"}, false},
+		{"path and paths", "/foo", "/bar", nil, true},
+		{"relative path", "api/v2", "", nil, true},
+		{"relative path in paths", "", "/foo,api/v2", nil, true},
+		{"empty path", "", "", nil, true},
+		{"without paths", "", " , ", nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-path")] = test.path
+		data[parser.GetAnnotationWithPrefix("abpolicy-paths")] = test.paths
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		paths := abConfig.PolicyPaths()
+		if strings.Join(paths, ",") != strings.Join(test.expPaths, ",") {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expPaths, paths)
+		}
+	}
+}
+
+func TestQueryParam(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "query"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err == nil {
+		t.Errorf("expected error parsing a query policy without abpolicy-query-param")
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy-query-param")] = "exp"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	abConfig := i.(*Config)
+	if abConfig.Type != PolicyTypeQuery {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", PolicyTypeQuery, abConfig.Type)
+	}
+	if abConfig.QueryParam != "exp" {
+		t.Errorf("expected \"exp\", but \"%v\" was returned", abConfig.QueryParam)
+	}
+
+	if abConfig.Equal(&Config{Type: PolicyTypeQuery, QueryParam: "variant"}) {
+		t.Errorf("expected configurations with different query parameters to be different")
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "false"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(i.(*Config).Warnings) != 1 {
+		t.Errorf("expected one warning for a staged policy without backends but %v returned", i.(*Config).Warnings)
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(i.(*Config).Warnings) != 0 {
+		t.Errorf("expected no warnings for a staged policy with backends but %v returned", i.(*Config).Warnings)
+	}
+
+	i, err = NewParser(&resolver.Mock{}).Parse(buildIngress())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(i.(*Config).Warnings) != 0 {
+		t.Errorf("expected no warnings without annotations but %v returned", i.(*Config).Warnings)
+	}
+}
+
+func TestPolicyWeight(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i.(*Config).Weight != 100 {
+		t.Errorf("expected default weight 100 but %v returned", i.(*Config).Weight)
+	}
+
+	tests := []struct {
+		title     string
+		weight    string
+		expWeight float32
+		expErr    bool
+	}{
+		{"fractional weight", "0.5", 0.5, false},
+		{"integer weight", "20", 20, false},
+		{"no requests", "0", 0, false},
+		{"negative weight", "-1", 0, true},
+		{"weight above 100", "100.5", 0, true},
+		{"invalid weight", "half", 0, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-weight")] = test.weight
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		if i.(*Config).Weight != test.expWeight {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expWeight, i.(*Config).Weight)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	c := Config{
+		Enabled: true,
+		Host:    "foo.bar.com",
+		Path:    "/foo",
+		Type:    PolicyTypeHeader,
+		Header:  "x-variant",
+		Backends: []*Backend{
+			{Name: "svc-a", Header: "v1"},
+			{Name: "svc-b", Headers: []string{"v2", "beta"}, Port: 8080},
+		},
+	}
+
+	expected := "{enabled=true host=foo.bar.com paths=/foo type=header header=x-variant " +
+		"backends=[{name=svc-a headers=v1 weight=0 port=0} {name=svc-b headers=v2,beta weight=0 port=8080}]}"
+	if c.String() != expected {
+		t.Errorf("expected \"%v\" but \"%v\" was returned", expected, c.String())
+	}
+
+	expected = "{enabled=false host= paths= type= header= backends=[]}"
+	if (Config{}).String() != expected {
+		t.Errorf("expected \"%v\" but \"%v\" was returned", expected, Config{}.String())
+	}
+
+	if fmt.Sprintf("%v", &c) != c.String() {
+		t.Errorf("expected formatting a pointer to use String")
+	}
+}
+
+func TestHashBy(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "weight"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","weight":90},{"name":"svc-b","weight":10}]`
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title     string
+		sticky    string
+		hashBy    string
+		expHashBy string
+		expErr    bool
+	}{
+		{"random weights", "", "", "", false},
+		{"sticky by remote address", "true", "remote_addr", "remote_addr", false},
+		{"sticky by header", "true", " x-user-id ", "x-user-id", false},
+		{"sticky without hash key", "true", "", "", true},
+		{"sticky with blank hash key", "true", "  ", "", true},
+		{"hash key with a dot", "true", "x.user", "", true},
+		{"hash key with a variable", "true", "$remote_addr", "", true},
+		{"hash key with spaces", "true", "x user", "", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-sticky")] = test.sticky
+		data[parser.GetAnnotationWithPrefix("abpolicy-hash-by")] = test.hashBy
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			if !errors.IsInvalidContent(err) {
+				t.Errorf("%v: expected an invalid content error but %v was returned", test.title, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.HashBy != test.expHashBy {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expHashBy, abConfig.HashBy)
+		}
+		if abConfig.Equal(&Config{HashBy: "cookie"}) {
+			t.Errorf("%v: expected configurations with different hash keys to be different", test.title)
+		}
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "percentage"
+	ing.SetAnnotations(data)
+
+	twoBackends := `[{"name":"svc-a"},{"name":"svc-b"}]`
+
+	tests := []struct {
+		title         string
+		percentage    string
+		backends      string
+		expPercentage int
+		expErr        bool
+	}{
+		{"lower bound", "0", twoBackends, 0, false},
+		{"split", "20", twoBackends, 20, false},
+		{"upper bound", "100", twoBackends, 100, false},
+		{"below range", "-1", twoBackends, 0, true},
+		{"above range", "101", twoBackends, 0, true},
+		{"not a number", "half", twoBackends, 0, true},
+		{"single backend", "20", `[{"name":"svc-a"}]`, 0, true},
+		{"three backends", "20", `[{"name":"svc-a"},{"name":"svc-b"},{"name":"svc-c"}]`, 0, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-percentage")] = test.percentage
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.Percentage != test.expPercentage {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expPercentage, abConfig.Percentage)
+		}
+	}
+}
+
+func TestNegate(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-query-param")] = "exp"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title     string
+		abType    string
+		backends  string
+		expNegate []bool
+		expErr    bool
+	}{
+		{"header match", "header", `[{"name":"svc-a","header":"v1"},{"name":"svc-b","header":"v1","negate":true}]`, []bool{false, true}, false},
+		{"negated cookie", "cookie", `[{"name":"svc-b","header":"v1","negate":true}]`, []bool{true}, false},
+		{"negated query", "query", `[{"name":"svc-b","header":"v1","negate":true}]`, []bool{true}, false},
+		{"negated weight", "weight", `[{"name":"svc-a","weight":1},{"name":"svc-b","weight":1,"negate":true}]`, nil, true},
+		{"negated percentage", "percentage", `[{"name":"svc-a"},{"name":"svc-b","negate":true}]`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-type")] = test.abType
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		for idx, b := range abConfig.Backends {
+			if b.Negate != test.expNegate[idx] {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expNegate[idx], b.Negate)
+			}
+		}
+	}
+
+	b1 := &Backend{Name: "svc-b", Header: "v1"}
+	b2 := b1.DeepCopy()
+	b2.Negate = true
+	if b1.Negate {
+		t.Errorf("expected original backend to be unchanged")
+	}
+	if b1.Equal(b2) {
+		t.Errorf("expected negated backend to be different")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	live := i.(*Config)
+	if live.DryRun {
+		t.Errorf("expected dry-run to be disabled by default")
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy-dry-run")] = "true"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dryRun := i.(*Config)
+	if !dryRun.DryRun {
+		t.Errorf("expected dry-run to be enabled")
+	}
+
+	if live.Equal(dryRun) {
+		t.Errorf("expected toggling dry-run to change the configuration")
+	}
+}
+
+func TestDisabledPolicyPaths(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "false"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = ""
+	ing.SetAnnotations(data)
+
+	_, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("expected a disabled policy to accept an empty path but %v returned", err)
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	ing.SetAnnotations(data)
+
+	_, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("expected an absolute path to be valid but %v returned", err)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	now := time.Now()
+
+	tests := []struct {
+		title      string
+		expiresAt  string
+		expEnabled bool
+		expErr     bool
+	}{
+		{"without expiration", "", true, false},
+		{"expiration in the future", now.Add(time.Hour).Format(time.RFC3339), true, false},
+		{"expiration in the past", now.Add(-time.Hour).Format(time.RFC3339), false, false},
+		{"malformed expiration", "tomorrow", false, true},
+		{"expiration without timezone", "2018-10-01T10:00:00", false, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-expires-at")] = test.expiresAt
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.IsEnabled(now) != test.expEnabled {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expEnabled, abConfig.IsEnabled(now))
+		}
+	}
+
+	c1 := &Config{ExpiresAt: now}
+	c2 := &Config{ExpiresAt: now.Add(time.Minute)}
+	if c1.Equal(c2) {
+		t.Errorf("expected configurations with different expiration to be different")
+	}
+}
+
+func TestMethods(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "method"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title      string
+		backends   string
+		expMethods []string
+		expErr     bool
+	}{
+		{"single method", `{"name":"svc-b","header":"POST"}`, []string{"POST"}, false},
+		{"list of methods", `{"name":"svc-b","header":"post, Put,delete"}`, []string{"POST", "PUT", "DELETE"}, false},
+		{"methods in headers", `{"name":"svc-b","headers":["POST,PUT","PATCH"]}`, []string{"POST", "PUT", "PATCH"}, false},
+		{"unknown method", `{"name":"svc-b","header":"POST,FETCH"}`, nil, true},
+		{"without methods", `{"name":"svc-b","header":" , "}`, nil, true},
+		{"methods set in the backend", `{"name":"svc-b","header":"GET","methods":["POST"]}`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		methods := i.(*Config).Backends[0].Methods
+		if strings.Join(methods, ",") != strings.Join(test.expMethods, ",") {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expMethods, methods)
+		}
+	}
+
+	// methods cannot be injected in policies of other types
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `{"name":"svc-b","header":"v2","methods":["POST"]}`
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected error for methods in a header policy but returned nil")
+	}
+
+	c := &Config{Type: PolicyTypeHeader, Backends: []*Backend{{Name: "svc-b", Header: "v2", Methods: []string{"POST"}}}}
+	if err := c.Validate(); err == nil {
+		t.Errorf("expected error validating methods in a header policy but returned nil")
+	}
+	c.Type = PolicyTypeMethod
+	c.Backends[0].Methods = []string{"FETCH"}
+	if err := c.Validate(); err == nil {
+		t.Errorf("expected error validating an unknown method but returned nil")
+	}
+}
+
+func TestCIDRs(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "cidr"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		backends string
+		expCIDRs []string
+		expErr   bool
+	}{
+		{"IPv4", `{"name":"svc-b","header":"10.0.0.0/8"}`, []string{"10.0.0.0/8"}, false},
+		{"IPv6", `{"name":"svc-b","header":"2001:db8::/32"}`, []string{"2001:db8::/32"}, false},
+		{"list of CIDRs", `{"name":"svc-b","header":"10.0.0.0/8, 192.168.1.0/24,2001:db8::/32"}`, []string{"10.0.0.0/8", "192.168.1.0/24", "2001:db8::/32"}, false},
+		{"address without mask", `{"name":"svc-b","header":"10.0.0.1"}`, nil, true},
+		{"malformed CIDR", `{"name":"svc-b","header":"10.0.0.0/8,10.0.0.300/24"}`, nil, true},
+		{"without CIDRs", `{"name":"svc-b","header":""}`, nil, true},
+		{"CIDRs set in the backend", `{"name":"svc-b","header":"10.0.0.0/8","cidrs":["0.0.0.0/0"]}`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		cidrs := i.(*Config).Backends[0].CIDRs
+		if strings.Join(cidrs, ",") != strings.Join(test.expCIDRs, ",") {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expCIDRs, cidrs)
+		}
+	}
+
+	// address ranges cannot be injected in policies of other types
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `{"name":"svc-b","header":"v2","cidrs":["10.0.0.0/8"]}`
+	if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+		t.Errorf("expected error for cidrs in a header policy but returned nil")
+	}
+
+	c := &Config{Type: PolicyTypeHeader, Backends: []*Backend{{Name: "svc-b", Header: "v2", CIDRs: []string{"10.0.0.0/8"}}}}
+	if err := c.Validate(); err == nil {
+		t.Errorf("expected error validating cidrs in a header policy but returned nil")
+	}
+	c.Type = PolicyTypeCIDR
+	c.Backends[0].CIDRs = []string{"10.0.0.300/24"}
+	if err := c.Validate(); err == nil {
+		t.Errorf("expected error validating a malformed CIDR but returned nil")
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	c := DefaultConfig()
+	if c.Enabled {
+		t.Errorf("expected default policy to be disabled")
+	}
+	if c.Backends == nil || len(c.Backends) != 0 {
+		t.Errorf("expected default policy to have an empty list of backends but %v returned", c.Backends)
+	}
+	if c.Host != "" || c.Path != "" || c.Type != "" || c.Header != "" {
+		t.Errorf("expected default policy without host, path, type and header but %v returned", c)
+	}
+	if c.Match != "" || c.MatchOrder != "" || c.Weight != 0 {
+		t.Errorf("expected default policy with zero values but %+v returned", c)
+	}
+
+	if !DefaultConfig().Equal(DefaultConfig()) {
+		t.Errorf("expected default policies to be equal")
+	}
+	if DefaultConfig() == DefaultConfig() {
+		t.Errorf("expected a new default policy on each call")
+	}
+
+	i, err := NewParser(&resolver.Mock{}).Parse(buildIngress())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !i.(*Config).Equal(DefaultConfig()) {
+		t.Errorf("expected an ingress without annotations to use the default policy but %v returned", i)
+	}
+
+	// the defaults of the annotations are applied to ingresses defining a policy
+	ing := buildIngress()
+	ing.SetAnnotations(map[string]string{parser.GetAnnotationWithPrefix("abpolicy-host"): "foo.bar.com"})
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c = i.(*Config)
+	if c.Match != MatchExact || c.MatchOrder != MatchOrderFirst || c.Weight != 100 {
+		t.Errorf("expected the defaults of the annotations but %+v returned", c)
+	}
+}
+
+func TestMatchSNI(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i.(*Config).MatchSNI {
+		t.Errorf("expected SNI matching to be disabled by default")
+	}
+
+	data[parser.GetAnnotationWithPrefix("abpolicy-match-sni")] = "true"
+	ing.SetAnnotations(data)
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i.(*Config).MatchSNI {
+		t.Errorf("expected SNI matching to be ignored without TLS")
+	}
+
+	ing.Spec.TLS = []extensions.IngressTLS{{Hosts: []string{"foo.bar.com"}, SecretName: "foo-tls"}}
+
+	i, err = NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !i.(*Config).MatchSNI {
+		t.Errorf("expected SNI matching to be enabled with TLS")
+	}
+
+	if i.(*Config).Equal(&Config{}) {
+		t.Errorf("expected configurations with different SNI matching to be different")
+	}
+}
+
+func TestValidWeights(t *testing.T) {
+	tests := []struct {
+		title    string
+		backends []*Backend
+		expected bool
+	}{
+		{"positive weights", []*Backend{{Name: "svc-a", Weight: 1}, {Name: "svc-b"}}, true},
+		{"zero total", []*Backend{{Name: "svc-a"}, {Name: "svc-b"}}, false},
+		{"negative weight", []*Backend{{Name: "svc-a", Weight: 2}, {Name: "svc-b", Weight: -1}}, false},
+		{"nil backend", []*Backend{{Name: "svc-a", Weight: 1}, nil}, false},
+	}
+
+	for _, test := range tests {
+		if valid := validWeights(test.backends); valid != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, valid)
+		}
+	}
+}
+
+func TestPresenceOnly(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "x-debug"
+	data[parser.GetAnnotationWithPrefix("abpolicy-query-param")] = "exp"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title       string
+		abType      string
+		backends    string
+		expPresence []bool
+		expErr      bool
+	}{
+		{"presence and value backends", "header", `[{"name":"svc-a","header":"v1"},{"name":"svc-b","presenceOnly":true}]`, []bool{false, true}, false},
+		{"empty value means presence", "header", `[{"name":"svc-a","headers":["v1","v2"]},{"name":"svc-b"}]`, []bool{false, true}, false},
+		{"presence with a value", "header", `[{"name":"svc-b","header":"v1","presenceOnly":true}]`, nil, true},
+		{"presence in cookie policy", "cookie", `[{"name":"svc-b","presenceOnly":true}]`, nil, true},
+		{"presence in query policy", "query", `[{"name":"svc-b","presenceOnly":true}]`, nil, true},
+		{"presence in weight policy", "weight", `[{"name":"svc-b","weight":1,"presenceOnly":true}]`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-type")] = test.abType
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if len(abConfig.Backends) != len(test.expPresence) {
+			t.Errorf("%v: expected %v backends but %v returned", test.title, len(test.expPresence), len(abConfig.Backends))
+			continue
+		}
+		for idx, b := range abConfig.Backends {
+			if b.PresenceOnly != test.expPresence[idx] {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expPresence[idx], b.PresenceOnly)
+			}
+		}
+	}
+
+	b1 := &Backend{Name: "svc-b"}
+	b2 := b1.DeepCopy()
+	b2.PresenceOnly = true
+	if b1.Equal(b2) {
+		t.Errorf("expected backends with different presence matching to be different")
+	}
+	if s := b2.String(); !strings.Contains(s, "headers=*") {
+		t.Errorf("expected presence-only backend to be rendered with a wildcard, but \"%v\" was returned", s)
+	}
+}
+
+func TestBackendPath(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "x-version"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title    string
+		backends string
+		expPath  string
+		expErr   bool
+	}{
+		{"backend without path", `[{"name":"svc-b","header":"v2"}]`, "", false},
+		{"backend with path", `[{"name":"svc-b","header":"v2","path":"/foo/api"}]`, "/foo/api", false},
+		{"relative path", `[{"name":"svc-b","header":"v2","path":"foo/api"}]`, "", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.Backends[0].Path != test.expPath {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expPath, abConfig.Backends[0].Path)
+		}
+	}
+
+	// the backend is selected when both the header and the path match
+	b := &Backend{Name: "svc-b", Header: "v2", Path: "/foo/api"}
+	matches := func(value, path string) bool {
+		for _, v := range b.HeaderValues() {
+			if v == value && b.MatchesPath(path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	combined := []struct {
+		title    string
+		value    string
+		path     string
+		expected bool
+	}{
+		{"header and path match", "v2", "/foo/api/users", true},
+		{"header matches", "v2", "/foo/web", false},
+		{"path matches", "v1", "/foo/api", false},
+		{"nothing matches", "v1", "/foo", false},
+	}
+
+	for _, test := range combined {
+		if m := matches(test.value, test.path); m != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, m)
+		}
+	}
+
+	if !(&Backend{Name: "svc-b"}).MatchesPath("/any") {
+		t.Errorf("expected a backend without path to match any path")
+	}
+
+	b2 := b.DeepCopy()
+	if !b.Equal(b2) {
+		t.Errorf("expected a copy of the backend to be equal")
+	}
+	b2.Path = "/foo"
+	if b.Equal(b2) {
+		t.Errorf("expected backends with different paths to be different")
+	}
+	if s := b.String(); !strings.Contains(s, "path=/foo/api") {
+		t.Errorf("expected the path in the backend representation, but \"%v\" was returned", s)
+	}
+}
+
+func TestWeightRanges(t *testing.T) {
+	newConfig := func(weights ...int) *Config {
+		c := &Config{Type: PolicyTypeWeight}
+		for i, w := range weights {
+			c.Backends = append(c.Backends, &Backend{Name: fmt.Sprintf("svc-%v", i), Weight: w})
+		}
+		return c
+	}
+
+	tests := []struct {
+		title    string
+		config   *Config
+		expected []WeightRange
+		expErr   bool
+	}{
+		{"three equal backends", newConfig(1, 1, 1), []WeightRange{{"svc-0", 0, 32}, {"svc-1", 33, 65}, {"svc-2", 66, 99}}, false},
+		{"two backends", newConfig(90, 10), []WeightRange{{"svc-0", 0, 89}, {"svc-1", 90, 99}}, false},
+		{"uneven weights", newConfig(1, 3), []WeightRange{{"svc-0", 0, 24}, {"svc-1", 25, 99}}, false},
+		{"zero weight last backend", newConfig(1, 2, 0), []WeightRange{{"svc-0", 0, 32}, {"svc-1", 33, 99}}, false},
+		{"single backend", newConfig(5), []WeightRange{{"svc-0", 0, 99}}, false},
+		{"tiny weight", newConfig(1000, 1, 1000), []WeightRange{{"svc-0", 0, 48}, {"svc-1", 49, 49}, {"svc-2", 50, 99}}, false},
+		{"tiny weight first", newConfig(1, 1000, 1000), []WeightRange{{"svc-0", 0, 0}, {"svc-1", 1, 49}, {"svc-2", 50, 99}}, false},
+		{"negative weight", newConfig(1, -1), nil, true},
+		{"all zero weights", newConfig(0, 0), nil, true},
+		{"header policy", &Config{Type: PolicyTypeHeader}, nil, true},
+		{"nil backend", &Config{Type: PolicyTypeWeight, Backends: []*Backend{{Name: "svc-a", Weight: 1}, nil}}, nil, true},
+	}
+
+	for _, test := range tests {
+		ranges, err := test.config.WeightRanges()
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		if len(ranges) != len(test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, ranges)
+			continue
+		}
+		for i := range ranges {
+			if ranges[i] != test.expected[i] {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected[i], ranges[i])
+			}
+		}
+
+		// every bucket is covered exactly once
+		next := 0
+		for _, r := range ranges {
+			if r.Start != next || r.End < r.Start {
+				t.Errorf("%v: range %v does not start at %v", test.title, r, next)
+			}
+			next = r.End + 1
+		}
+		if next != 100 {
+			t.Errorf("%v: expected the ranges to cover 100 buckets, but %v are covered", test.title, next)
+		}
+	}
+}
+
+func TestWeightRangesMinimumBucket(t *testing.T) {
+	c := &Config{Type: PolicyTypeWeight, Backends: []*Backend{{Name: "svc-big", Weight: 1000}}}
+	for i := 0; i < 59; i++ {
+		c.Backends = append(c.Backends, &Backend{Name: fmt.Sprintf("svc-%v", i), Weight: 1})
+	}
+
+	ranges, err := c.WeightRanges()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ranges) != len(c.Backends) {
+		t.Fatalf("expected a range for each of the %v backends, but %v were returned", len(c.Backends), len(ranges))
+	}
+	if ranges[0].End != 40 {
+		t.Errorf("expected the largest backend to give up buckets, but \"%v\" was returned", ranges[0])
+	}
+	if ranges[len(ranges)-1].End != 99 {
+		t.Errorf("expected the ranges to cover 100 buckets, but \"%v\" was returned", ranges)
+	}
+
+	for i := 0; i < 41; i++ {
+		c.Backends = append(c.Backends, &Backend{Name: fmt.Sprintf("svc-extra-%v", i), Weight: 1})
+	}
+	if _, err := c.WeightRanges(); err == nil {
+		t.Errorf("expected an error for more than 100 backends receiving traffic")
+	}
+}
+
+func TestMatchOrder(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "User-Agent"
+	data[parser.GetAnnotationWithPrefix("abpolicy-match")] = "regex"
+	// both backends match a request from an Android device
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","header":"Android"},{"name":"svc-b","header":"Mobile"}]`
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title      string
+		matchOrder string
+		expOrder   string
+		expErr     bool
+	}{
+		{"default order", "", MatchOrderFirst, false},
+		{"first match", "first", MatchOrderFirst, false},
+		{"last match", "last", MatchOrderLast, false},
+		{"order is normalized", "Last", MatchOrderLast, false},
+		{"unknown order", "random", "", true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-match-order")] = test.matchOrder
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.MatchOrder != test.expOrder {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expOrder, abConfig.MatchOrder)
+		}
+
+	}
+
+	c1 := &Config{MatchOrder: MatchOrderFirst}
+	if !c1.Equal(&Config{}) {
+		t.Errorf("expected an empty match order to be equal to %v", MatchOrderFirst)
+	}
+	if c1.Equal(&Config{MatchOrder: MatchOrderLast}) {
+		t.Errorf("expected policies with different match order to be different")
+	}
+	if err := (&Config{MatchOrder: "random"}).Validate(); err == nil {
+		t.Errorf("expected an unknown match order to be invalid")
+	}
+}
+
+func TestUnreachableBackends(t *testing.T) {
+	tests := []struct {
+		title    string
+		config   *Config
+		expected []string
+	}{
+		{"no shadowing", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", Header: "v1"},
+			{Name: "svc-b", Header: "v2"},
+		}}, []string{}},
+		{"duplicate header value", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", Headers: []string{"v1", "v2"}},
+			{Name: "svc-b", Header: "v2"},
+		}}, []string{"svc-b"}},
+		{"duplicate header value with last match", &Config{Type: PolicyTypeHeader, MatchOrder: MatchOrderLast, Backends: []*Backend{
+			{Name: "svc-a", Headers: []string{"v1", "v2"}},
+			{Name: "svc-b", Header: "v2"},
+		}}, []string{}},
+		{"partially covered values", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", Header: "v1"},
+			{Name: "svc-b", Headers: []string{"v1", "v2"}},
+		}}, []string{}},
+		{"different path", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", Header: "v1", Path: "/api"},
+			{Name: "svc-b", Header: "v1"},
+		}}, []string{}},
+		{"shadowed by presence", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", PresenceOnly: true},
+			{Name: "svc-b", Header: "v1", Path: "/api"},
+		}}, []string{"svc-b"}},
+		{"duplicate method", &Config{Type: PolicyTypeMethod, Backends: []*Backend{
+			{Name: "svc-a", Methods: []string{"GET", "HEAD"}},
+			{Name: "svc-b", Methods: []string{"HEAD"}},
+		}}, []string{"svc-b"}},
+		{"negated backend", &Config{Type: PolicyTypeHeader, Backends: []*Backend{
+			{Name: "svc-a", Header: "v1"},
+			{Name: "svc-b", Header: "v1", Negate: true},
+		}}, []string{}},
+		{"weight policy", &Config{Type: PolicyTypeWeight, Backends: []*Backend{
+			{Name: "svc-a", Weight: 50},
+			{Name: "svc-b", Weight: 50},
+		}}, nil},
+	}
+
+	for _, test := range tests {
+		unreachable := test.config.UnreachableBackends()
+		if !reflect.DeepEqual(unreachable, test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, unreachable)
+		}
+	}
+
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "X-Variant"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","header":"v1"},{"name":"svc-b","header":"v1"}]`
+	data[parser.GetAnnotationWithPrefix("abpolicy-skip-backend-validation")] = "true"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(i.(*Config).Warnings) != 1 {
+		t.Errorf("expected one warning for a shadowed backend but %v returned", i.(*Config).Warnings)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	tests := []struct {
+		title       string
+		annotations map[string]string
+		expected    error
+	}{
+		{"invalid expiration", map[string]string{"abpolicy-expires-at": "tomorrow"}, ErrInvalidExpiration},
+		{"invalid host", map[string]string{"abpolicy-host": "foo_bar"}, ErrInvalidHost},
+		{"relative path", map[string]string{"abpolicy-path": "foo"}, ErrInvalidPath},
+		{"unknown type", map[string]string{"abpolicy-type": "random"}, ErrInvalidType},
+		{"unknown match", map[string]string{"abpolicy-match": "random"}, ErrInvalidMatch},
+		{"invalid weight", map[string]string{"abpolicy-weight": "heavy"}, ErrInvalidWeight},
+		{"malformed backends", map[string]string{"abpolicy-backends": `[{"name":"svc-a"`}, ErrInvalidBackends},
+		{"empty backends", map[string]string{"abpolicy-backends": `[]`}, ErrEmptyBackends},
+		{"unknown service", map[string]string{"abpolicy-backends": `[{"name":"svc-x","header":"v1"}]`}, ErrUnknownService},
+		{"sticky header policy", map[string]string{"abpolicy-sticky": "true"}, ErrInvalidPolicy},
+	}
+
+	for _, test := range tests {
+		ing := buildIngress()
+
+		data := map[string]string{}
+		data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+		data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+		data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+		data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+		data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "X-Variant"
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-a","header":"v1"}]`
+		for k, v := range test.annotations {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if err == nil {
+			t.Errorf("%v: expected error but returned nil", test.title)
+			continue
+		}
+		if errorKind(err) != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, err)
+		}
+		if !errors.IsInvalidContent(err) {
+			t.Errorf("%v: expected an annotation error but \"%v\" was returned", test.title, err)
+		}
+	}
+}
+
+func TestMirrorBackend(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title         string
+		policyType    string
+		mirrorBackend string
+		backends      string
+		skip          string
+		expErr        error
+	}{
+		{"mirror policy", "mirror", "svc-b", "", "", nil},
+		{"mirror policy without mirror backend", "mirror", "", "", "", ErrEmptyBackends},
+		{"unknown mirror backend", "mirror", "svc-x", "", "", ErrUnknownService},
+		{"unknown mirror backend without validation", "mirror", "svc-x", "", "true", nil},
+		{"mirror policy with backends", "mirror", "svc-b", `[{"name":"svc-b","header":"v2"}]`, "", ErrInvalidBackends},
+		{"mirror backend of a header policy", "header", "svc-b", `[{"name":"svc-b","header":"v2"}]`, "", ErrInvalidBackends},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-type")] = test.policyType
+		data[parser.GetAnnotationWithPrefix("abpolicy-mirror-backend")] = test.mirrorBackend
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+		data[parser.GetAnnotationWithPrefix("abpolicy-skip-backend-validation")] = test.skip
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr != nil {
+			if errorKind(err) != test.expErr {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if abConfig.MirrorBackend != test.mirrorBackend {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.mirrorBackend, abConfig.MirrorBackend)
+		}
+		if len(abConfig.Warnings) != 0 {
+			t.Errorf("%v: expected no warnings but %v returned", test.title, abConfig.Warnings)
+		}
+	}
+
+	c1 := &Config{Type: PolicyTypeMirror, MirrorBackend: "svc-a"}
+	c2 := &Config{Type: PolicyTypeMirror, MirrorBackend: "svc-b"}
+	if c1.Equal(c2) {
+		t.Errorf("expected configurations with different mirror backends to be different")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	newConfig := func(backends ...*Backend) *Config {
+		return &Config{
+			Enabled:  true,
+			Host:     "foo.bar.com",
+			Path:     "/foo",
+			Type:     PolicyTypeWeight,
+			Backends: backends,
+		}
+	}
+
+	c1 := newConfig(&Backend{Name: "svc-a", Weight: 80}, &Backend{Name: "svc-b", Weight: 20})
+
+	fp := c1.Fingerprint()
+	if len(fp) != 64 {
+		t.Fatalf("expected a SHA-256 hex digest but \"%v\" was returned", fp)
+	}
+	if c1.Fingerprint() != fp {
+		t.Errorf("expected the fingerprint to be stable")
+	}
+
+	tests := []struct {
+		title  string
+		config *Config
+		equal  bool
+	}{
+		{"reordered backends", newConfig(&Backend{Name: "svc-b", Weight: 20}, &Backend{Name: "svc-a", Weight: 80}), true},
+		{"different warnings", func() *Config {
+			c := newConfig(&Backend{Name: "svc-a", Weight: 80}, &Backend{Name: "svc-b", Weight: 20})
+			c.Warnings = []string{"warning"}
+			return c
+		}(), true},
+		{"changed weight", newConfig(&Backend{Name: "svc-a", Weight: 70}, &Backend{Name: "svc-b", Weight: 30}), false},
+		{"renamed backend", newConfig(&Backend{Name: "svc-a", Weight: 80}, &Backend{Name: "svc-c", Weight: 20}), false},
+	}
+
+	for _, test := range tests {
+		if (test.config.Fingerprint() == fp) != test.equal {
+			t.Errorf("%v: expected equal fingerprints to be %v", test.title, test.equal)
+		}
+	}
+
+	if c1.Backends[0].Name != "svc-a" {
+		t.Errorf("expected the backends of the policy to keep their order")
+	}
+
+	// the first matching backend is selected, so the order changes the routing
+	h1 := newConfig(&Backend{Name: "svc-a", Header: "v1"}, &Backend{Name: "svc-b", Header: "v1"})
+	h1.Type = PolicyTypeHeader
+	h2 := newConfig(&Backend{Name: "svc-b", Header: "v1"}, &Backend{Name: "svc-a", Header: "v1"})
+	h2.Type = PolicyTypeHeader
+	if h1.Fingerprint() == h2.Fingerprint() {
+		t.Errorf("expected header policies with reordered backends to have different fingerprints")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	configs := []*Config{
+		DefaultConfig(),
+		{
+			Enabled:        true,
+			Host:           "foo.bar.com",
+			Paths:          []string{"/foo", "/bar"},
+			Type:           PolicyTypeHeader,
+			Header:         "X-Variant",
+			Match:          MatchRegex,
+			MatchOrder:     MatchOrderLast,
+			Weight:         50,
+			DefaultBackend: "svc-a",
+			ExpiresAt:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			Backends: []*Backend{
+				{Name: "svc-b", Headers: []string{"v2", "v3"}, Port: 8080, Path: "/foo"},
+				{Name: "svc-c", PresenceOnly: true, Negate: true},
+			},
+		},
+		{
+			Enabled:    true,
+			Host:       "foo.bar.com",
+			Path:       "/foo",
+			Type:       PolicyTypeCIDR,
+			Percentage: 30,
+			Backends: []*Backend{
+				{Name: "svc-b", CIDRs: []string{"10.0.0.0/8"}},
+				{Name: "svc-c", Methods: []string{"GET"}, Weight: 10},
+			},
+		},
+	}
+
+	for _, c := range configs {
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("unexpected error encoding %v: %v", c, err)
+		}
+
+		out := &Config{}
+		err = json.Unmarshal(data, out)
+		if err != nil {
+			t.Fatalf("unexpected error decoding %s: %v", data, err)
+		}
+
+		if !c.Equal(out) {
+			t.Errorf("expected \"%v\", but \"%v\" was returned", c, out)
+		}
+		if c.Fingerprint() != out.Fingerprint() {
+			t.Errorf("expected the fingerprint of %s to survive the round trip", data)
+		}
+	}
+}
+
+func TestBackendJSONKeys(t *testing.T) {
+	b := &Backend{Name: "svc-b", Header: "v2"}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error encoding %v: %v", b, err)
+	}
+
+	expected := `{"name":"svc-b","header":"v2"}`
+	if string(data) != expected {
+		t.Errorf("expected \"%v\", but \"%s\" was returned", expected, data)
+	}
+
+	// every field must have a well-formed lower camel case json tag
+	bt := reflect.TypeOf(Backend{})
+	for i := 0; i < bt.NumField(); i++ {
+		field := bt.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || strings.ToLower(name[:1]) != name[:1] {
+			t.Errorf("field %v has an invalid json tag \"%v\"", field.Name, field.Tag)
+		}
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "X-Variant"
+
+	tests := []struct {
+		title    string
+		backends string
+		expected map[string]string
+		expErr   bool
+	}{
+		{"without headers", `[{"name":"svc-b","header":"v2"}]`, nil, false},
+		{"with headers", `[{"name":"svc-b","header":"v2","setHeaders":{"x-variant":"b","x-cohort":"beta"}}]`,
+			map[string]string{"x-variant": "b", "x-cohort": "beta"}, false},
+		{"empty header name", `[{"name":"svc-b","header":"v2","setHeaders":{" ":"b"}}]`, nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		b := i.(*Config).Backends[0]
+		if !reflect.DeepEqual(b.SetHeaders, test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, b.SetHeaders)
+		}
+
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("%v: unexpected error encoding %v: %v", test.title, b, err)
+		}
+		decoded := &Backend{}
+		err = json.Unmarshal(encoded, decoded)
+		if err != nil {
+			t.Fatalf("%v: unexpected error decoding %s: %v", test.title, encoded, err)
+		}
+		if !b.Equal(decoded) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, b, decoded)
+		}
+	}
+
+	b1 := &Backend{Name: "svc-b", SetHeaders: map[string]string{"x-variant": "b"}}
+	b2 := b1.DeepCopy()
+	if !b1.Equal(b2) {
+		t.Fatalf("expected copy to be equal to the original")
+	}
+
+	b2.SetHeaders["x-variant"] = "c"
+	if b1.SetHeaders["x-variant"] != "b" {
+		t.Errorf("expected original headers to be unchanged but %v returned", b1.SetHeaders)
+	}
+	if b1.Equal(b2) {
+		t.Errorf("expected backends with different headers to be different")
+	}
+}
+
+func TestExcludePaths(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/api"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+	data[parser.GetAnnotationWithPrefix("abpolicy-header")] = "X-Variant"
+	data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = `[{"name":"svc-b","header":"v2"}]`
+
+	tests := []struct {
+		title        string
+		excludePaths string
+		expected     []string
+		expErr       bool
+	}{
+		{"without excluded paths", "", nil, false},
+		{"excluded paths", "/api/health,/api/ready", []string{"/api/health", "/api/ready"}, false},
+		{"relative excluded path", "/api/health,ready", nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-exclude-paths")] = test.excludePaths
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if errorKind(err) != ErrInvalidPath {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, ErrInvalidPath, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		abConfig := i.(*Config)
+		if !reflect.DeepEqual(abConfig.ExcludePaths, test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, abConfig.ExcludePaths)
+		}
+
+		if abConfig.IsExcluded("/api/users") {
+			t.Errorf("%v: expected /api/users not to be excluded", test.title)
+		}
+
+		if excluded := abConfig.IsExcluded("/api/health"); excluded != (len(test.expected) > 0) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned for /api/health", test.title, len(test.expected) > 0, excluded)
+		}
+	}
+
+	c1 := &Config{ExcludePaths: []string{"/api/health"}}
+	if c1.Equal(&Config{ExcludePaths: []string{"/api/ready"}}) {
+		t.Errorf("expected configurations with different excluded paths to be different")
+	}
+	if err := (&Config{ExcludePaths: []string{"health"}}).Validate(); err == nil {
+		t.Errorf("expected a relative excluded path to be invalid")
+	}
+}
+
+func TestHeaderNames(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "header"
+
+	tests := []struct {
+		title    string
+		header   string
+		backends string
+		expErr   bool
+	}{
+		{"valid header", "X-Variant", `[{"name":"svc-b","header":"v2"}]`, false},
+		{"header with symbols", "x_variant.v2~", `[{"name":"svc-b","header":"v2"}]`, false},
+		{"header with spaces", "X Variant", `[{"name":"svc-b","header":"v2"}]`, true},
+		{"header with a colon", "X-Variant:", `[{"name":"svc-b","header":"v2"}]`, true},
+		{"valid set header", "X-Variant", `[{"name":"svc-b","header":"v2","setHeaders":{"X-Cohort":"b"}}]`, false},
+		{"set header with spaces", "X-Variant", `[{"name":"svc-b","header":"v2","setHeaders":{"X Cohort":"b"}}]`, true},
+		{"set header with a colon", "X-Variant", `[{"name":"svc-b","header":"v2","setHeaders":{"X-Cohort:":"b"}}]`, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("abpolicy-header")] = test.header
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = test.backends
+		ing.SetAnnotations(data)
+
+		_, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if errorKind(err) != ErrInvalidHeader {
+				t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, ErrInvalidHeader, err)
+			}
+			if !errors.IsInvalidContent(err) {
+				t.Errorf("%v: expected an invalid content error but %v was returned", test.title, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+		}
+	}
+}
+
+func TestSortedWeightRanges(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("abpolicy")] = "true"
+	data[parser.GetAnnotationWithPrefix("abpolicy-host")] = "foo.bar.com"
+	data[parser.GetAnnotationWithPrefix("abpolicy-path")] = "/foo"
+	data[parser.GetAnnotationWithPrefix("abpolicy-type")] = "weight"
+
+	// the same backends defined in different orders
+	backends := []string{
+		`[{"name":"svc-a","weight":1},{"name":"svc-b","weight":1},{"name":"svc-c","weight":1}]`,
+		`[{"name":"svc-c","weight":1},{"name":"svc-a","weight":1},{"name":"svc-b","weight":1}]`,
+		`[{"name":"svc-b","weight":1},{"name":"svc-c","weight":1},{"name":"svc-a","weight":1}]`,
+	}
+
+	expected := []WeightRange{
+		{Backend: "svc-a", Start: 0, End: 32},
+		{Backend: "svc-b", Start: 33, End: 65},
+		{Backend: "svc-c", Start: 66, End: 99},
+	}
+
+	var first []byte
+	for _, b := range backends {
+		data[parser.GetAnnotationWithPrefix("abpolicy-backends")] = b
+		ing.SetAnnotations(data)
+
+		// each configuration is generated twice, as two replicas of the controller would do
+		for i := 0; i < 2; i++ {
+			p, err := NewParser(&resolver.Mock{}).Parse(ing)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %v: %v", b, err)
+			}
+
+			ranges, err := p.(*Config).SortedWeightRanges()
+			if err != nil {
+				t.Fatalf("unexpected error building the ranges of %v: %v", b, err)
+			}
+			if !reflect.DeepEqual(ranges, expected) {
+				t.Errorf("expected \"%v\", but \"%v\" was returned", expected, ranges)
+			}
+
+			out, err := json.Marshal(ranges)
+			if err != nil {
+				t.Fatalf("unexpected error encoding %v: %v", ranges, err)
+			}
+			if first == nil {
+				first = out
+				continue
+			}
+			if !bytes.Equal(first, out) {
+				t.Errorf("expected \"%s\", but \"%s\" was returned for %v", first, out, b)
+			}
+		}
+	}
+
+	c := &Config{Type: PolicyTypeWeight, Backends: []*Backend{{Name: "svc-b", Weight: 1}, {Name: "svc-a", Weight: 3}}}
+	if _, err := c.SortedWeightRanges(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Backends[0].Name != "svc-b" {
+		t.Errorf("expected the backends of the policy to keep their order")
+	}
+
+	if _, err := (&Config{Type: PolicyTypeHeader}).SortedWeightRanges(); err == nil {
+		t.Errorf("expected an error for a header policy")
+	}
+
+	c = &Config{Type: PolicyTypeWeight, Backends: []*Backend{{Name: "svc-b", Weight: 1}, nil, {Name: "svc-a", Weight: 3}}}
+	if _, err := c.SortedWeightRanges(); err == nil {
+		t.Errorf("expected an error for a nil backend")
+	}
+}
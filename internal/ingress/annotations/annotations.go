@@ -27,6 +27,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/ingress-nginx/internal/ingress/annotations/abpolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/alias"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
@@ -67,6 +68,7 @@ const DeniedKeyName = "Denied"
 // Ingress defines the valid annotations present in one NGINX Ingress rule
 type Ingress struct {
 	metav1.ObjectMeta
+	ABPolicy             abpolicy.Config
 	BackendProtocol      string
 	Alias                string
 	BasicDigestAuth      auth.Config
@@ -100,6 +102,9 @@ type Ingress struct {
 	LuaRestyWAF          luarestywaf.Config
 	InfluxDB             influxdb.Config
 	ModSecurity          modsecurity.Config
+	// InvalidContent contains, by annotation parser, the errors of the annotations
+	// ignored because their value is not valid
+	InvalidContent map[string]error
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
@@ -111,6 +116,7 @@ type Extractor struct {
 func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 	return Extractor{
 		map[string]parser.IngressAnnotation{
+			"ABPolicy":             abpolicy.NewParser(cfg),
 			"Alias":                alias.NewParser(cfg),
 			"BasicDigestAuth":      auth.NewParser(auth.AuthDirectory, cfg),
 			"Canary":               canary.NewParser(cfg),
@@ -154,6 +160,7 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 	}
 
 	data := make(map[string]interface{})
+	invalid := make(map[string]error)
 	for name, annotationParser := range e.annotations {
 		val, err := annotationParser.Parse(ing)
 		glog.V(5).Infof("annotation %v in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), val)
@@ -162,6 +169,10 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 				continue
 			}
 
+			if errors.IsInvalidContent(err) {
+				invalid[name] = err
+			}
+
 			if !errors.IsLocationDenied(err) {
 				continue
 			}
@@ -194,5 +205,9 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 		glog.Errorf("unexpected error merging extracted annotations: %v", err)
 	}
 
+	if len(invalid) > 0 {
+		pia.InvalidContent = invalid
+	}
+
 	return pia
 }
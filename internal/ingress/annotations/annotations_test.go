@@ -307,6 +307,37 @@ func TestCustomHTTPErrors(t *testing.T) {
 	}
 }
 
+func TestInvalidContent(t *testing.T) {
+	ec := NewAnnotationExtractor(mockCfg{})
+	ing := buildIngress()
+
+	fooAnns := []struct {
+		annotations map[string]string
+		er          []string
+	}{
+		{map[string]string{parser.GetAnnotationWithPrefix("abpolicy-type"): "random"}, []string{"ABPolicy"}},
+		{map[string]string{parser.GetAnnotationWithPrefix("abpolicy-type"): "header"}, []string{}},
+		{map[string]string{}, []string{}},
+		{nil, []string{}},
+	}
+
+	for _, foo := range fooAnns {
+		ing.SetAnnotations(foo.annotations)
+		r := ec.Extract(ing).InvalidContent
+
+		if len(r) != len(foo.er) {
+			t.Errorf("Returned %v but expected %v", r, foo.er)
+			continue
+		}
+
+		for _, name := range foo.er {
+			if r[name] == nil {
+				t.Errorf("Returned %v but expected %v", r, foo.er)
+			}
+		}
+	}
+}
+
 /*
 func TestMergeLocationAnnotations(t *testing.T) {
 	// initial parameters
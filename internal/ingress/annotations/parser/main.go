@@ -17,8 +17,11 @@ limitations under the License.
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
@@ -57,6 +60,21 @@ func (a ingAnnotations) parseString(name string) (string, error) {
 	return "", errors.ErrMissingAnnotations
 }
 
+func (a ingAnnotations) parseStringSlice(name string) ([]string, error) {
+	val, ok := a[name]
+	if ok {
+		values := []string{}
+		for _, v := range strings.Split(val, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	}
+	return nil, errors.ErrMissingAnnotations
+}
+
 func (a ingAnnotations) parseInt(name string) (int, error) {
 	val, ok := a[name]
 	if ok {
@@ -69,6 +87,42 @@ func (a ingAnnotations) parseInt(name string) (int, error) {
 	return 0, errors.ErrMissingAnnotations
 }
 
+func (a ingAnnotations) parseFloat32(name string) (float32, error) {
+	val, ok := a[name]
+	if ok {
+		f, err := strconv.ParseFloat(val, 32)
+		if err != nil {
+			return 0, errors.NewInvalidAnnotationContent(name, val)
+		}
+		return float32(f), nil
+	}
+	return 0, errors.ErrMissingAnnotations
+}
+
+func (a ingAnnotations) parseDuration(name string) (time.Duration, error) {
+	val, ok := a[name]
+	if ok {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, errors.NewInvalidAnnotationContent(name, val)
+		}
+		return d, nil
+	}
+	return 0, errors.ErrMissingAnnotations
+}
+
+func (a ingAnnotations) parseJSON(name string, out interface{}) error {
+	val, ok := a[name]
+	if ok {
+		err := json.Unmarshal([]byte(val), out)
+		if err != nil {
+			return errors.NewInvalidAnnotationContent(name, err)
+		}
+		return nil
+	}
+	return errors.ErrMissingAnnotations
+}
+
 func checkAnnotation(name string, ing *extensions.Ingress) error {
 	if ing == nil || len(ing.GetAnnotations()) == 0 {
 		return errors.ErrMissingAnnotations
@@ -100,6 +154,17 @@ func GetStringAnnotation(name string, ing *extensions.Ingress) (string, error) {
 	return ingAnnotations(ing.GetAnnotations()).parseString(v)
 }
 
+// GetStringSliceAnnotation extracts a comma-separated list of strings from an Ingress annotation.
+// Whitespace around each element is removed and empty elements are dropped.
+func GetStringSliceAnnotation(name string, ing *extensions.Ingress) ([]string, error) {
+	v := GetAnnotationWithPrefix(name)
+	err := checkAnnotation(v, ing)
+	if err != nil {
+		return nil, err
+	}
+	return ingAnnotations(ing.GetAnnotations()).parseStringSlice(v)
+}
+
 // GetIntAnnotation extracts an int from an Ingress annotation
 func GetIntAnnotation(name string, ing *extensions.Ingress) (int, error) {
 	v := GetAnnotationWithPrefix(name)
@@ -110,6 +175,37 @@ func GetIntAnnotation(name string, ing *extensions.Ingress) (int, error) {
 	return ingAnnotations(ing.GetAnnotations()).parseInt(v)
 }
 
+// GetFloatAnnotation extracts a float32 from an Ingress annotation
+func GetFloatAnnotation(name string, ing *extensions.Ingress) (float32, error) {
+	v := GetAnnotationWithPrefix(name)
+	err := checkAnnotation(v, ing)
+	if err != nil {
+		return 0, err
+	}
+	return ingAnnotations(ing.GetAnnotations()).parseFloat32(v)
+}
+
+// GetDurationAnnotation extracts a duration, i.e. 30s or 5m, from an Ingress annotation
+func GetDurationAnnotation(name string, ing *extensions.Ingress) (time.Duration, error) {
+	v := GetAnnotationWithPrefix(name)
+	err := checkAnnotation(v, ing)
+	if err != nil {
+		return 0, err
+	}
+	return ingAnnotations(ing.GetAnnotations()).parseDuration(v)
+}
+
+// GetJSONAnnotation extracts a JSON document from an Ingress annotation
+// and decodes it into the value pointed to by out
+func GetJSONAnnotation(name string, ing *extensions.Ingress, out interface{}) error {
+	v := GetAnnotationWithPrefix(name)
+	err := checkAnnotation(v, ing)
+	if err != nil {
+		return err
+	}
+	return ingAnnotations(ing.GetAnnotations()).parseJSON(v, out)
+}
+
 // GetAnnotationWithPrefix returns the prefix of ingress annotations
 func GetAnnotationWithPrefix(suffix string) string {
 	return fmt.Sprintf("%v/%v", AnnotationsPrefix, suffix)
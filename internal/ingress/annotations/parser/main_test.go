@@ -17,11 +17,15 @@ limitations under the License.
 package parser
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	api "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/errors"
 )
 
 func buildIngress() *extensions.Ingress {
@@ -153,3 +157,220 @@ func TestGetIntAnnotation(t *testing.T) {
 		delete(data, test.field)
 	}
 }
+
+func TestGetFloatAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := GetFloatAnnotation("", nil)
+	if err == nil {
+		t.Errorf("expected error but retuned nil")
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		value  string
+		exp    float32
+		expErr bool
+	}{
+		{"valid - float", "float", "0.5", 0.5, false},
+		{"valid - integer", "float", "10", 10, false},
+		{"valid - negative", "float", "-2.25", -2.25, false},
+		{"invalid - garbage", "float", "half", 0, true},
+		{"invalid - empty", "float", "", 0, true},
+	}
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	for _, test := range tests {
+		data[GetAnnotationWithPrefix(test.field)] = test.value
+
+		f, err := GetFloatAnnotation(test.field, ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.name)
+			}
+			continue
+		}
+		if f != test.exp {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.name, test.exp, f)
+		}
+
+		delete(data, GetAnnotationWithPrefix(test.field))
+	}
+
+	_, err = GetFloatAnnotation("missing", ing)
+	if err != errors.ErrMissingAnnotations {
+		t.Errorf("expected ErrMissingAnnotations but %v returned", err)
+	}
+}
+
+func TestGetDurationAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := GetDurationAnnotation("", nil)
+	if err == nil {
+		t.Errorf("expected error but retuned nil")
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		value  string
+		exp    time.Duration
+		expErr bool
+	}{
+		{"valid - seconds", "duration", "30s", 30 * time.Second, false},
+		{"valid - minutes", "duration", "5m", 5 * time.Minute, false},
+		{"valid - combined", "duration", "1h30m", 90 * time.Minute, false},
+		{"valid - negative", "duration", "-10s", -10 * time.Second, false},
+		{"invalid - garbage", "duration", "soon", 0, true},
+		{"invalid - without unit", "duration", "30", 0, true},
+		{"invalid - empty", "duration", "", 0, true},
+	}
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	for _, test := range tests {
+		data[GetAnnotationWithPrefix(test.field)] = test.value
+
+		d, err := GetDurationAnnotation(test.field, ing)
+		if test.expErr {
+			if !errors.IsInvalidContent(err) {
+				t.Errorf("%v: expected an invalid content error but %v returned", test.name, err)
+			}
+			continue
+		}
+		if d != test.exp {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.name, test.exp, d)
+		}
+
+		delete(data, GetAnnotationWithPrefix(test.field))
+	}
+
+	_, err = GetDurationAnnotation("missing", ing)
+	if err != errors.ErrMissingAnnotations {
+		t.Errorf("expected ErrMissingAnnotations but %v returned", err)
+	}
+}
+
+func TestGetJSONAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	err := GetJSONAnnotation("", nil, &struct{}{})
+	if err == nil {
+		t.Errorf("expected error but retuned nil")
+	}
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		value  string
+		exp    []item
+		expErr bool
+	}{
+		{"valid - list", "json", `[{"name":"a"},{"name":"b"}]`, []item{{"a"}, {"b"}}, false},
+		{"valid - empty list", "json", `[]`, []item{}, false},
+		{"invalid - malformed", "json", `[{"name":"a"}`, nil, true},
+		{"invalid - wrong type", "json", `{"name":"a"}`, nil, true},
+	}
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	for _, test := range tests {
+		data[GetAnnotationWithPrefix(test.field)] = test.value
+
+		var items []item
+		err := GetJSONAnnotation(test.field, ing, &items)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.name)
+			}
+			if !errors.IsInvalidContent(err) {
+				t.Errorf("%v: expected an invalid content error but %v returned", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+			continue
+		}
+		if len(items) != len(test.exp) {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.name, test.exp, items)
+			continue
+		}
+		for i := range items {
+			if items[i] != test.exp[i] {
+				t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.name, test.exp, items)
+			}
+		}
+
+		delete(data, GetAnnotationWithPrefix(test.field))
+	}
+
+	err = GetJSONAnnotation("missing", ing, &struct{}{})
+	if err != errors.ErrMissingAnnotations {
+		t.Errorf("expected ErrMissingAnnotations but %v returned", err)
+	}
+}
+
+func TestGetStringSliceAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	_, err := GetStringSliceAnnotation("", nil)
+	if err == nil {
+		t.Errorf("expected error but retuned nil")
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		value  string
+		exp    []string
+		expErr bool
+	}{
+		{"valid - single", "slice", "a", []string{"a"}, false},
+		{"valid - list", "slice", "a,b,c", []string{"a", "b", "c"}, false},
+		{"valid - embedded spaces", "slice", " a , b ,c ", []string{"a", "b", "c"}, false},
+		{"valid - trailing comma", "slice", "a,b,", []string{"a", "b"}, false},
+		{"valid - empty elements", "slice", ",a,,b", []string{"a", "b"}, false},
+		{"valid - whitespace", "slice", "   ", []string{}, false},
+		{"valid - empty", "slice", "", []string{}, false},
+	}
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	for _, test := range tests {
+		data[GetAnnotationWithPrefix(test.field)] = test.value
+
+		s, err := GetStringSliceAnnotation(test.field, ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+			continue
+		}
+		if s == nil || strings.Join(s, "|") != strings.Join(test.exp, "|") {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.name, test.exp, s)
+		}
+
+		delete(data, GetAnnotationWithPrefix(test.field))
+	}
+
+	_, err = GetStringSliceAnnotation("missing", ing)
+	if err != errors.ErrMissingAnnotations {
+		t.Errorf("expected ErrMissingAnnotations but %v returned", err)
+	}
+}
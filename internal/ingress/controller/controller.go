@@ -35,9 +35,11 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/abpolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/task"
 )
 
 const (
@@ -126,6 +128,8 @@ func (n *NGINXController) syncIngress(interface{}) error {
 	})
 
 	upstreams, servers := n.getBackendServers(ings)
+	n.scheduleABPolicyExpiration(ings)
+
 	var passUpstreams []*ingress.SSLPassthroughBackend
 
 	hosts := sets.NewString()
@@ -390,6 +394,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 	for _, ing := range ingresses {
 		ingKey := k8s.MetaNamespaceKey(ing)
 		anns := ing.ParsedAnnotations
+		abPolicy := abPolicyServicePorts(ing, anns.ABPolicy, n.store.GetService)
 
 		for _, rule := range ing.Spec.Rules {
 			host := rule.Host
@@ -484,6 +489,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						loc.BackendProtocol = anns.BackendProtocol
 						loc.CustomHTTPErrors = anns.CustomHTTPErrors
 						loc.ModSecurity = anns.ModSecurity
+						loc.ABPolicy = abPolicyAt(abPolicy, time.Now())
 
 						if loc.Redirect.FromToWWW {
 							server.RedirectFromToWWW = true
@@ -526,6 +532,7 @@ func (n *NGINXController) getBackendServers(ingresses []*ingress.Ingress) ([]*in
 						BackendProtocol:      anns.BackendProtocol,
 						CustomHTTPErrors:     anns.CustomHTTPErrors,
 						ModSecurity:          anns.ModSecurity,
+						ABPolicy:             abPolicyAt(abPolicy, time.Now()),
 					}
 
 					if loc.Redirect.FromToWWW {
@@ -1221,6 +1228,104 @@ func extractTLSSecretName(host string, ing *ingress.Ingress,
 	return ""
 }
 
+// abPolicyAt returns the A/B policy of a location as it is applied at the given time.
+// An expired policy is disabled, so the expiration changes the location and the
+// configuration is reloaded by the sync scheduled at the expiration.
+func abPolicyAt(policy abpolicy.Config, now time.Time) abpolicy.Config {
+	if policy.Enabled && !policy.IsEnabled(now) {
+		policy.Enabled = false
+	}
+
+	return policy
+}
+
+// abPolicyServicePorts returns the A/B policy of an Ingress with the ports of its
+// backends checked against the ports of their Services. A backend using a port its
+// Service does not expose falls back to the port referenced by the Ingress.
+func abPolicyServicePorts(ing *ingress.Ingress, policy abpolicy.Config,
+	getService func(string) (*apiv1.Service, error)) abpolicy.Config {
+
+	var checked *abpolicy.Config
+	for i, b := range policy.Backends {
+		if b == nil || b.Port == 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%v/%v", ing.Namespace, b.Name)
+		svc, err := getService(key)
+		if err != nil {
+			glog.Warningf("Error getting Service %q of the abpolicy of Ingress %q: %v", key, k8s.MetaNamespaceKey(ing), err)
+			continue
+		}
+
+		found := false
+		for _, sp := range svc.Spec.Ports {
+			if int(sp.Port) == b.Port {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		glog.Warningf("Service %q does not expose port %v used by the abpolicy of Ingress %q, using the port referenced by the Ingress",
+			key, b.Port, k8s.MetaNamespaceKey(ing))
+		if checked == nil {
+			checked = policy.DeepCopy()
+		}
+		checked.Backends[i].Port = 0
+	}
+
+	if checked == nil {
+		return policy
+	}
+
+	return *checked
+}
+
+// nextABPolicyExpiration returns the earliest expiration, after now, of the enabled
+// A/B policies of the Ingresses, or the zero time if none of them expires.
+func nextABPolicyExpiration(ingresses []*ingress.Ingress, now time.Time) time.Time {
+	var next time.Time
+	for _, ing := range ingresses {
+		if ing.ParsedAnnotations == nil {
+			continue
+		}
+
+		policy := ing.ParsedAnnotations.ABPolicy
+		if !policy.IsEnabled(now) || policy.ExpiresAt.IsZero() {
+			continue
+		}
+
+		if next.IsZero() || policy.ExpiresAt.Before(next) {
+			next = policy.ExpiresAt
+		}
+	}
+
+	return next
+}
+
+// scheduleABPolicyExpiration enqueues a sync of the configuration at the earliest
+// expiration of the A/B policies, replacing the previously scheduled one.
+func (n *NGINXController) scheduleABPolicyExpiration(ingresses []*ingress.Ingress) {
+	if n.abPolicyTimer != nil {
+		n.abPolicyTimer.Stop()
+		n.abPolicyTimer = nil
+	}
+
+	now := time.Now()
+	next := nextABPolicyExpiration(ingresses, now)
+	if next.IsZero() {
+		return
+	}
+
+	glog.V(3).Infof("Scheduling a sync at the expiration of an A/B policy (%v)", next)
+	n.abPolicyTimer = time.AfterFunc(next.Sub(now), func() {
+		n.syncQueue.EnqueueTask(task.GetDummyObject("abpolicy-expiration"))
+	})
+}
+
 // getRemovedHosts returns a list of the hostsnames
 // that are not associated anymore to the NGINX configuration.
 func getRemovedHosts(rucfg, newcfg *ingress.Configuration) []string {
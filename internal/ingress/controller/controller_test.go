@@ -20,13 +20,18 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"fmt"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/abpolicy"
 )
 
 func TestMergeAlternativeBackends(t *testing.T) {
@@ -361,3 +366,118 @@ func fakeX509Cert(dnsNames []string) *x509.Certificate {
 		},
 	}
 }
+
+func TestABPolicyAt(t *testing.T) {
+	now := time.Date(2018, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		policy     abpolicy.Config
+		expEnabled bool
+	}{
+		"enabled policy without expiration": {abpolicy.Config{Enabled: true}, true},
+		"enabled policy not expired":        {abpolicy.Config{Enabled: true, ExpiresAt: now.Add(time.Minute)}, true},
+		"enabled policy expired":            {abpolicy.Config{Enabled: true, ExpiresAt: now}, false},
+		"disabled policy":                   {abpolicy.Config{ExpiresAt: now.Add(time.Minute)}, false},
+	}
+
+	for title, tc := range testCases {
+		policy := abPolicyAt(tc.policy, now)
+		if policy.Enabled != tc.expEnabled {
+			t.Errorf("%v: expected enabled %v but %v returned", title, tc.expEnabled, policy.Enabled)
+		}
+		if !policy.ExpiresAt.Equal(tc.policy.ExpiresAt) {
+			t.Errorf("%v: expected the expiration to be kept", title)
+		}
+	}
+
+	policy := abpolicy.Config{Enabled: true, ExpiresAt: now}
+	expired := abPolicyAt(policy, now)
+	if policy.Equal(&expired) {
+		t.Errorf("expected the expiration to change the policy of the location")
+	}
+}
+
+func TestABPolicyServicePorts(t *testing.T) {
+	ing := &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "example",
+			},
+		},
+	}
+
+	getService := func(key string) (*apiv1.Service, error) {
+		if key != "example/http-svc" {
+			return nil, fmt.Errorf("service %v not found", key)
+		}
+		return &apiv1.Service{
+			Spec: apiv1.ServiceSpec{
+				Ports: []apiv1.ServicePort{{Port: 80}, {Port: 8080}},
+			},
+		}, nil
+	}
+
+	testCases := map[string]struct {
+		port    int
+		service string
+		expPort int
+	}{
+		"default port":                       {0, "http-svc", 0},
+		"port of the service":                {8080, "http-svc", 8080},
+		"port not exposed by the service":    {9090, "http-svc", 0},
+		"port of a service not in the store": {9090, "unknown", 9090},
+	}
+
+	for title, tc := range testCases {
+		policy := abpolicy.Config{
+			Enabled:  true,
+			Backends: []*abpolicy.Backend{{Name: tc.service, Port: tc.port}},
+		}
+
+		checked := abPolicyServicePorts(ing, policy, getService)
+		if checked.Backends[0].Port != tc.expPort {
+			t.Errorf("%v: expected port %v but %v returned", title, tc.expPort, checked.Backends[0].Port)
+		}
+		if policy.Backends[0].Port != tc.port {
+			t.Errorf("%v: expected the policy of the ingress to be kept", title)
+		}
+	}
+}
+
+func TestNextABPolicyExpiration(t *testing.T) {
+	now := time.Date(2018, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	newIngress := func(policy abpolicy.Config) *ingress.Ingress {
+		return &ingress.Ingress{
+			ParsedAnnotations: &annotations.Ingress{ABPolicy: policy},
+		}
+	}
+
+	testCases := map[string]struct {
+		ingresses []*ingress.Ingress
+		expected  time.Time
+	}{
+		"without ingresses": {nil, time.Time{}},
+		"without expiration": {[]*ingress.Ingress{
+			newIngress(abpolicy.Config{Enabled: true}),
+		}, time.Time{}},
+		"earliest expiration": {[]*ingress.Ingress{
+			newIngress(abpolicy.Config{Enabled: true, ExpiresAt: now.Add(time.Hour)}),
+			newIngress(abpolicy.Config{Enabled: true, ExpiresAt: now.Add(time.Minute)}),
+			{},
+		}, now.Add(time.Minute)},
+		"expired and disabled policies": {[]*ingress.Ingress{
+			newIngress(abpolicy.Config{Enabled: true, ExpiresAt: now}),
+			newIngress(abpolicy.Config{ExpiresAt: now.Add(time.Minute)}),
+			newIngress(abpolicy.Config{Enabled: true, ExpiresAt: now.Add(time.Hour)}),
+		}, now.Add(time.Hour)},
+	}
+
+	for title, tc := range testCases {
+		next := nextABPolicyExpiration(tc.ingresses, now)
+		if !next.Equal(tc.expected) {
+			t.Errorf("%v: expected %v but %v returned", title, tc.expected, next)
+		}
+	}
+}
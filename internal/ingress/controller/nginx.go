@@ -258,6 +258,9 @@ type NGINXController struct {
 	fileSystem filesystem.Filesystem
 
 	metricCollector metric.Collector
+
+	// abPolicyTimer enqueues a sync when the next A/B policy expires
+	abPolicyTimer *time.Timer
 }
 
 // Start starts a new NGINX master process running in the foreground.
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -222,6 +223,9 @@ type k8sStore struct {
 	isDynamicCertificatesEnabled bool
 
 	pod *k8s.PodInfo
+
+	// recorder emits the events of the ingresses and configmaps
+	recorder record.EventRecorder
 }
 
 // New creates a new object store to be used in the ingress controller
@@ -257,6 +261,7 @@ func New(checkOCSP bool,
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
 		Component: "nginx-ingress-controller",
 	})
+	store.recorder = recorder
 
 	// k8sStore fulfills resolver.Resolver interface
 	store.annotations = annotations.NewAnnotationExtractor(store)
@@ -599,12 +604,58 @@ func (s *k8sStore) extractAnnotations(ing *extensions.Ingress) {
 
 	anns := s.annotations.Extract(ing)
 
-	err := s.listers.IngressAnnotation.Update(anns)
+	// the annotations are also extracted when a secret or the configuration
+	// changes, so the errors are only reported the first time they are found
+	old, err := s.listers.IngressAnnotation.ByKey(key)
+	if err != nil || !sameInvalidContent(old.InvalidContent, anns.InvalidContent) {
+		names := make([]string, 0, len(anns.InvalidContent))
+		for name := range anns.InvalidContent {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			s.recorder.Eventf(ing, corev1.EventTypeWarning, "InvalidAnnotationContent", "%v", anns.InvalidContent[name])
+		}
+	}
+	if err != nil || !sameWarnings(old.ABPolicy.Warnings, anns.ABPolicy.Warnings) {
+		for _, msg := range anns.ABPolicy.Warnings {
+			s.recorder.Eventf(ing, corev1.EventTypeWarning, "ABPolicyWarning", "%v", msg)
+		}
+	}
+
+	err = s.listers.IngressAnnotation.Update(anns)
 	if err != nil {
 		glog.Error(err)
 	}
 }
 
+// sameInvalidContent returns true if both maps contain the same errors
+func sameInvalidContent(a, b map[string]error) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, err := range a {
+		other, ok := b[name]
+		if !ok || err.Error() != other.Error() {
+			return false
+		}
+	}
+	return true
+}
+
+// sameWarnings returns true if both slices contain the same warnings
+func sameWarnings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // updateSecretIngressMap takes an Ingress and updates all Secret objects it
 // references in secretIngressMap.
 func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
@@ -19,6 +19,7 @@ package store
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -30,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	"encoding/base64"
 	"io/ioutil"
@@ -38,6 +40,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/k8s"
 	"k8s.io/ingress-nginx/test/e2e/framework"
@@ -830,6 +833,75 @@ func TestUpdateSecretIngressMap(t *testing.T) {
 	})
 }
 
+func TestExtractAnnotationsInvalidContentEvents(t *testing.T) {
+	s := newStore(t)
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	recorder := record.NewFakeRecorder(10)
+	s.recorder = recorder
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("abpolicy"):       "true",
+				parser.GetAnnotationWithPrefix("abpolicy-match"): "invalid",
+			},
+		},
+	}
+
+	s.extractAnnotations(ing)
+	if l := len(recorder.Events); l != 1 {
+		t.Fatalf("Expected 1 event for the invalid annotation (got %d)", l)
+	}
+	<-recorder.Events
+
+	// a secret or the configuration changed, the errors are the same
+	s.extractAnnotations(ing)
+	if l := len(recorder.Events); l != 0 {
+		t.Errorf("Expected no event when the errors do not change (got %d)", l)
+	}
+
+	ing.Annotations[parser.GetAnnotationWithPrefix("abpolicy-match")] = "other"
+	s.extractAnnotations(ing)
+	if l := len(recorder.Events); l != 1 {
+		t.Errorf("Expected 1 event when the errors change (got %d)", l)
+	}
+}
+
+func TestExtractAnnotationsABPolicyWarningEvents(t *testing.T) {
+	s := newStore(t)
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	recorder := record.NewFakeRecorder(10)
+	s.recorder = recorder
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("abpolicy"):        "false",
+				parser.GetAnnotationWithPrefix("abpolicy-type"):   "header",
+				parser.GetAnnotationWithPrefix("abpolicy-header"): "X-Canary",
+			},
+		},
+	}
+
+	s.extractAnnotations(ing)
+	if l := len(recorder.Events); l != 1 {
+		t.Fatalf("Expected 1 event for the abpolicy warning (got %d)", l)
+	}
+	e := <-recorder.Events
+	if !strings.HasPrefix(e, "Warning ABPolicyWarning ") {
+		t.Errorf("Expected a Warning event with reason ABPolicyWarning (got %v)", e)
+	}
+
+	s.extractAnnotations(ing)
+	if l := len(recorder.Events); l != 0 {
+		t.Errorf("Expected no event when the warnings do not change (got %d)", l)
+	}
+}
+
 func TestListIngresses(t *testing.T) {
 	s := newStore(t)
 
@@ -26,6 +26,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	text_template "text/template"
@@ -37,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/abpolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
@@ -125,6 +127,9 @@ var (
 		"buildLocation":              buildLocation,
 		"buildAuthLocation":          buildAuthLocation,
 		"buildAuthResponseHeaders":   buildAuthResponseHeaders,
+		"buildABPolicy":              buildABPolicy,
+		"buildMirrorLocation":        buildMirrorLocation,
+		"buildMirrorUpstream":        buildMirrorUpstream,
 		"buildProxyPass":             buildProxyPass,
 		"filterRateLimits":           filterRateLimits,
 		"buildRateLimitZones":        buildRateLimitZones,
@@ -373,6 +378,312 @@ func buildAuthLocation(input interface{}) string {
 	return fmt.Sprintf("/_external-auth-%v", str)
 }
 
+// buildMirrorUpstream returns the name of the upstream receiving a copy of the
+// requests of the location when it is affected by an enabled mirror abpolicy
+// and its path is not excluded. The host of the policy is checked for each
+// request by abpolicy.lua.
+func buildMirrorUpstream(input interface{}) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	policy := location.ABPolicy
+	if policy.Type != abpolicy.PolicyTypeMirror || !appliesABPolicy(location) {
+		return ""
+	}
+
+	upstream := abPolicyUpstream(location.Ingress, policy.MirrorBackend, 0)
+	if upstream == "" {
+		glog.Warningf("mirror backend %v is not a service of Ingress %v/%v", policy.MirrorBackend, location.Ingress.Namespace, location.Ingress.Name)
+	}
+
+	return upstream
+}
+
+// buildMirrorLocation returns the internal location proxying the copy of the
+// requests of the location to its mirror upstream, or an empty string
+func buildMirrorLocation(input interface{}) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	if buildMirrorUpstream(location) == "" {
+		return ""
+	}
+
+	str := base64.URLEncoding.EncodeToString([]byte(location.Path))
+	// removes "=" after encoding
+	str = strings.Replace(str, "=", "", -1)
+	return fmt.Sprintf("/_abpolicy-mirror-%v", str)
+}
+
+// abPolicyLua is the configuration of an abpolicy evaluated by abpolicy.lua
+type abPolicyLua struct {
+	Host string `json:"host"`
+	SNI  bool   `json:"sni"`
+	// Split selects the backend by bucket instead of comparing Variable with its values
+	Split bool `json:"split"`
+	// MirrorUpstream receives a copy of the requests of mirror policies
+	MirrorUpstream  string               `json:"mirrorUpstream,omitempty"`
+	Variable        string               `json:"variable"`
+	Match           string               `json:"match"`
+	Sample          float32              `json:"sample"`
+	HashBy          string               `json:"hashBy"`
+	DryRun          bool                 `json:"dryRun"`
+	ExcludePaths    []string             `json:"excludePaths"`
+	DefaultUpstream string               `json:"defaultUpstream"`
+	Backends        []abPolicyLuaBackend `json:"backends"`
+}
+
+// abPolicyLuaBackend is a backend of an abpolicy evaluated by abpolicy.lua
+type abPolicyLuaBackend struct {
+	Upstream     string            `json:"upstream"`
+	Values       []string          `json:"values"`
+	CIDRs        []abPolicyLuaCIDR `json:"cidrs,omitempty"`
+	PresenceOnly bool              `json:"presenceOnly"`
+	Negate       bool              `json:"negate"`
+	Path         string            `json:"path,omitempty"`
+	SetHeaders   map[string]string `json:"setHeaders,omitempty"`
+	// From and To are the inclusive range of buckets of the backend in split policies
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// abPolicyLuaCIDR is an address range compared with $binary_remote_addr
+type abPolicyLuaCIDR struct {
+	Network []int `json:"network"`
+	Bits    int   `json:"bits"`
+}
+
+// buildABPolicy returns the configuration, as a Lua string, of the abpolicy routing or
+// mirroring the requests of the location, or an empty string when the location is not
+// affected by an enabled policy. The host of the policy, its excluded paths and the values
+// of the backends are compared with the request by abpolicy.lua.
+func buildABPolicy(input interface{}) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	policy := location.ABPolicy
+	if !appliesABPolicy(location) {
+		return ""
+	}
+
+	ing := location.Ingress
+	lp := abPolicyLua{
+		Host:         policy.Host,
+		SNI:          policy.MatchSNI,
+		Match:        policy.Match,
+		Sample:       policy.Weight,
+		DryRun:       policy.DryRun,
+		ExcludePaths: []string{},
+		Backends:     []abPolicyLuaBackend{},
+	}
+	lp.ExcludePaths = append(lp.ExcludePaths, policy.ExcludePaths...)
+
+	if policy.DefaultBackend != "" {
+		lp.DefaultUpstream = abPolicyUpstream(ing, policy.DefaultBackend, 0)
+	}
+
+	if policy.Type == abpolicy.PolicyTypeMirror {
+		lp.MirrorUpstream = buildMirrorUpstream(location)
+		if lp.MirrorUpstream == "" {
+			return ""
+		}
+	}
+
+	switch policy.Type {
+	case abpolicy.PolicyTypeHeader:
+		lp.Variable = "http_" + strings.Replace(strings.ToLower(policy.Header), "-", "_", -1)
+	case abpolicy.PolicyTypeCookie:
+		lp.Variable = "cookie_" + policy.Header
+	case abpolicy.PolicyTypeQuery:
+		lp.Variable = "arg_" + policy.QueryParam
+	case abpolicy.PolicyTypeMethod:
+		lp.Variable = "request_method"
+	case abpolicy.PolicyTypeCIDR:
+		lp.Variable = "binary_remote_addr"
+	case abpolicy.PolicyTypeWeight, abpolicy.PolicyTypePercentage:
+		lp.Split = true
+	}
+	if lp.Variable == "http_" || lp.Variable == "cookie_" {
+		glog.Warningf("abpolicy of Ingress %v/%v does not define the header to inspect", ing.Namespace, ing.Name)
+		return ""
+	}
+
+	if policy.Sticky {
+		switch policy.HashBy {
+		case "remote_addr":
+			lp.HashBy = "remote_addr"
+		case "cookie":
+			lp.HashBy = "http_cookie"
+			if policy.Header != "" {
+				lp.HashBy = "cookie_" + policy.Header
+			}
+		default:
+			lp.HashBy = "http_" + strings.Replace(strings.ToLower(policy.HashBy), "-", "_", -1)
+		}
+	}
+
+	ranges := map[string]abpolicy.WeightRange{}
+	switch policy.Type {
+	case abpolicy.PolicyTypeWeight:
+		wr, err := policy.SortedWeightRanges()
+		if err != nil {
+			glog.Warningf("invalid weights in abpolicy of Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+			return ""
+		}
+		for _, r := range wr {
+			ranges[r.Backend] = r
+		}
+	case abpolicy.PolicyTypePercentage:
+		if len(policy.Backends) != 2 || policy.Backends[0] == nil || policy.Backends[1] == nil {
+			glog.Warningf("percentage abpolicy of Ingress %v/%v requires two backends", ing.Namespace, ing.Name)
+			return ""
+		}
+		ranges[policy.Backends[0].Name] = abpolicy.WeightRange{Start: 0, End: policy.Percentage - 1}
+		ranges[policy.Backends[1].Name] = abpolicy.WeightRange{Start: policy.Percentage, End: 99}
+	}
+
+	backends := make([]*abpolicy.Backend, 0, len(policy.Backends))
+	for _, b := range policy.Backends {
+		if b != nil {
+			backends = append(backends, b)
+		}
+	}
+	// abpolicy.lua selects the first matching backend
+	if policy.MatchOrder == abpolicy.MatchOrderLast {
+		for i, j := 0, len(backends)-1; i < j; i, j = i+1, j-1 {
+			backends[i], backends[j] = backends[j], backends[i]
+		}
+	}
+
+	for _, b := range backends {
+		upstream := abPolicyUpstream(ing, b.Name, b.Port)
+		if upstream == "" {
+			glog.Warningf("abpolicy backend %v is not a service of Ingress %v/%v", b.Name, ing.Namespace, ing.Name)
+			continue
+		}
+
+		lb := abPolicyLuaBackend{
+			Upstream:     upstream,
+			Values:       []string{},
+			PresenceOnly: b.PresenceOnly,
+			Negate:       b.Negate,
+			Path:         b.Path,
+			SetHeaders:   b.SetHeaders,
+		}
+
+		switch policy.Type {
+		case abpolicy.PolicyTypeMethod:
+			lb.Values = append(lb.Values, b.Methods...)
+		case abpolicy.PolicyTypeCIDR:
+			for _, c := range b.CIDRs {
+				_, network, err := net.ParseCIDR(c)
+				if err != nil {
+					glog.Warningf("invalid CIDR %v in abpolicy of Ingress %v/%v", c, ing.Namespace, ing.Name)
+					continue
+				}
+				ip := network.IP
+				if ip4 := ip.To4(); ip4 != nil {
+					ip = ip4
+				}
+				bits, _ := network.Mask.Size()
+				lc := abPolicyLuaCIDR{Network: make([]int, len(ip)), Bits: bits}
+				for i := range ip {
+					lc.Network[i] = int(ip[i])
+				}
+				lb.CIDRs = append(lb.CIDRs, lc)
+			}
+		case abpolicy.PolicyTypeWeight, abpolicy.PolicyTypePercentage:
+			r, ok := ranges[b.Name]
+			if !ok {
+				continue
+			}
+			lb.From, lb.To = r.Start, r.End
+		default:
+			lb.Values = append(lb.Values, b.HeaderValues()...)
+		}
+
+		lp.Backends = append(lp.Backends, lb)
+	}
+
+	// the ranges do not depend on the order used to define the backends
+	if lp.Split {
+		sort.SliceStable(lp.Backends, func(i, j int) bool {
+			return lp.Backends[i].From < lp.Backends[j].From
+		})
+	}
+
+	data, err := json.Marshal(lp)
+	if err != nil {
+		glog.Errorf("unexpected error encoding abpolicy of Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+		return ""
+	}
+
+	return luaLongString(string(data))
+}
+
+// appliesABPolicy returns true when the location is affected by an enabled abpolicy
+// and its path is not excluded. The host of the policy is checked for each request.
+func appliesABPolicy(location *ingress.Location) bool {
+	policy := location.ABPolicy
+	// the expiration of the policy is evaluated by the controller
+	if policy.Type == "" || !policy.Enabled || location.Ingress == nil {
+		return false
+	}
+
+	for _, p := range policy.PolicyPaths() {
+		if p == location.Path {
+			return !policy.IsExcluded(location.Path)
+		}
+	}
+
+	return false
+}
+
+// abPolicyUpstream returns the name of the upstream of a service of the ingress.
+// When port is zero, the port referenced by the ingress for the service is used.
+func abPolicyUpstream(ing *ingress.Ingress, service string, port int) string {
+	if port != 0 {
+		return fmt.Sprintf("%v-%v-%v", ing.Namespace, service, port)
+	}
+
+	if ing.Spec.Backend != nil && ing.Spec.Backend.ServiceName == service {
+		return fmt.Sprintf("%v-%v-%v", ing.Namespace, service, ing.Spec.Backend.ServicePort.String())
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.ServiceName == service {
+				return fmt.Sprintf("%v-%v-%v", ing.Namespace, service, path.Backend.ServicePort.String())
+			}
+		}
+	}
+
+	return ""
+}
+
+// luaLongString returns s as a Lua long bracket string, which does not interpret
+// escape sequences. The level of the brackets is increased until they do not
+// appear in s.
+func luaLongString(s string) string {
+	level := ""
+	for strings.Contains(s, "]"+level+"]") {
+		level += "="
+	}
+
+	return fmt.Sprintf("[%v[%v]%v]", level, s, level)
+}
+
 func buildAuthResponseHeaders(input interface{}) []string {
 	location, ok := input.(*ingress.Location)
 	res := []string{}
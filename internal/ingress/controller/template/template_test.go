@@ -29,8 +29,12 @@ import (
 	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/abpolicy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
@@ -454,6 +458,59 @@ func TestBuildAuthLocation(t *testing.T) {
 	}
 }
 
+func TestBuildMirrorLocation(t *testing.T) {
+	ing := &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: extensions.IngressSpec{
+				Rules: []extensions.IngressRule{{
+					Host: "foo.bar.com",
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{
+								{Path: "/cat", Backend: extensions.IngressBackend{ServiceName: "svc-a", ServicePort: intstr.FromInt(80)}},
+								{Path: "/dog", Backend: extensions.IngressBackend{ServiceName: "svc-b", ServicePort: intstr.FromInt(8080)}},
+							},
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	encodedPath := strings.Replace(base64.URLEncoding.EncodeToString([]byte("/cat")), "=", "", -1)
+
+	tests := []struct {
+		title            string
+		policy           abpolicy.Config
+		expectedLocation string
+		expectedUpstream string
+	}{
+		{"mirror policy", abpolicy.Config{Enabled: true, Type: abpolicy.PolicyTypeMirror, Path: "/cat", MirrorBackend: "svc-b"},
+			fmt.Sprintf("/_abpolicy-mirror-%v", encodedPath), "default-svc-b-8080"},
+		{"disabled mirror policy", abpolicy.Config{Type: abpolicy.PolicyTypeMirror, Path: "/cat", MirrorBackend: "svc-b"}, "", ""},
+		{"mirror policy excluding the path", abpolicy.Config{Enabled: true, Type: abpolicy.PolicyTypeMirror, Path: "/cat", ExcludePaths: []string{"/cat"}, MirrorBackend: "svc-b"}, "", ""},
+		{"mirror policy of another path", abpolicy.Config{Enabled: true, Type: abpolicy.PolicyTypeMirror, Path: "/dog", MirrorBackend: "svc-b"}, "", ""},
+		{"unknown mirror backend", abpolicy.Config{Enabled: true, Type: abpolicy.PolicyTypeMirror, Path: "/cat", MirrorBackend: "svc-x"}, "", ""},
+		{"header policy", abpolicy.Config{Enabled: true, Type: abpolicy.PolicyTypeHeader, Path: "/cat"}, "", ""},
+	}
+
+	for _, test := range tests {
+		loc := &ingress.Location{
+			Path:     "/cat",
+			Ingress:  ing,
+			ABPolicy: test.policy,
+		}
+
+		if str := buildMirrorLocation(loc); str != test.expectedLocation {
+			t.Errorf("%v: expected '%v' but returned '%v'", test.title, test.expectedLocation, str)
+		}
+		if str := buildMirrorUpstream(loc); str != test.expectedUpstream {
+			t.Errorf("%v: expected '%v' but returned '%v'", test.title, test.expectedUpstream, str)
+		}
+	}
+}
+
 func TestBuildAuthResponseHeaders(t *testing.T) {
 	loc := &ingress.Location{
 		ExternalAuth: authreq.Config{ResponseHeaders: []string{"h1", "H-With-Caps-And-Dashes"}},
@@ -834,3 +891,140 @@ func TestEscapeLiteralDollar(t *testing.T) {
 		t.Errorf("Expected %v but returned %v", expected, escapedPath)
 	}
 }
+
+func TestBuildABPolicy(t *testing.T) {
+	ing := &ingress.Ingress{
+		Ingress: extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: extensions.IngressSpec{
+				Rules: []extensions.IngressRule{{
+					Host: "foo.bar.com",
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{
+								{Path: "/cat", Backend: extensions.IngressBackend{ServiceName: "svc-a", ServicePort: intstr.FromInt(80)}},
+								{Path: "/dog", Backend: extensions.IngressBackend{ServiceName: "svc-b", ServicePort: intstr.FromString("http")}},
+							},
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	policy := func(t abpolicy.PolicyType, backends ...*abpolicy.Backend) abpolicy.Config {
+		return abpolicy.Config{Enabled: true, Host: "foo.bar.com", Path: "/cat", Type: t, Header: "X-Variant", Weight: 100, Backends: backends}
+	}
+	expected := func(variable string, backends ...abPolicyLuaBackend) *abPolicyLua {
+		return &abPolicyLua{Host: "foo.bar.com", Variable: variable, Sample: 100, ExcludePaths: []string{}, Backends: backends}
+	}
+
+	headerPolicy := policy(abpolicy.PolicyTypeHeader, &abpolicy.Backend{Name: "svc-a", Header: "v1"}, &abpolicy.Backend{Name: "svc-b", Headers: []string{"v2", "beta"}, SetHeaders: map[string]string{"x-variant": "b"}})
+	lastPolicy := headerPolicy
+	lastPolicy.MatchOrder = abpolicy.MatchOrderLast
+	dryRunPolicy := headerPolicy
+	dryRunPolicy.DryRun = true
+	dryRunPolicy.DefaultBackend = "svc-b"
+	disabledPolicy := headerPolicy
+	disabledPolicy.Enabled = false
+	otherPathPolicy := headerPolicy
+	otherPathPolicy.Path = "/dog"
+	stickyPolicy := policy(abpolicy.PolicyTypeWeight, &abpolicy.Backend{Name: "svc-b", Weight: 1}, &abpolicy.Backend{Name: "svc-a", Weight: 3})
+	stickyPolicy.Sticky = true
+	stickyPolicy.HashBy = "remote_addr"
+	percentagePolicy := policy(abpolicy.PolicyTypePercentage, &abpolicy.Backend{Name: "svc-a"}, &abpolicy.Backend{Name: "svc-b"})
+	percentagePolicy.Percentage = 30
+	queryPolicy := policy(abpolicy.PolicyTypeQuery, &abpolicy.Backend{Name: "svc-b", Header: "v2", Negate: true})
+	queryPolicy.QueryParam = "exp"
+
+	v1 := abPolicyLuaBackend{Upstream: "default-svc-a-80", Values: []string{"v1"}}
+	v2 := abPolicyLuaBackend{Upstream: "default-svc-b-http", Values: []string{"v2", "beta"}, SetHeaders: map[string]string{"x-variant": "b"}}
+
+	dryRunExpected := expected("http_x_variant", v1, v2)
+	dryRunExpected.DryRun = true
+	dryRunExpected.DefaultUpstream = "default-svc-b-http"
+	stickyExpected := expected("", abPolicyLuaBackend{Upstream: "default-svc-a-80", Values: []string{}, From: 0, To: 74}, abPolicyLuaBackend{Upstream: "default-svc-b-http", Values: []string{}, From: 75, To: 99})
+	stickyExpected.Split = true
+	stickyExpected.HashBy = "remote_addr"
+	percentageExpected := expected("", abPolicyLuaBackend{Upstream: "default-svc-a-80", Values: []string{}, From: 0, To: 29}, abPolicyLuaBackend{Upstream: "default-svc-b-http", Values: []string{}, From: 30, To: 99})
+	percentageExpected.Split = true
+
+	mirrorPolicy := abpolicy.Config{Enabled: true, Host: "*.bar.com", MatchSNI: true, DryRun: true, Type: abpolicy.PolicyTypeMirror, Path: "/cat", Weight: 50, MirrorBackend: "svc-b"}
+	mirrorExpected := expected("")
+	mirrorExpected.Host = "*.bar.com"
+	mirrorExpected.SNI = true
+	mirrorExpected.DryRun = true
+	mirrorExpected.Sample = 50
+	mirrorExpected.MirrorUpstream = "default-svc-b-http"
+	mirrorExpected.Backends = []abPolicyLuaBackend{}
+
+	tests := []struct {
+		title    string
+		policy   abpolicy.Config
+		expected *abPolicyLua
+	}{
+		{"header policy", headerPolicy, expected("http_x_variant", v1, v2)},
+		{"header policy with last match order", lastPolicy, expected("http_x_variant", v2, v1)},
+		{"dry-run policy with default backend", dryRunPolicy, dryRunExpected},
+		{"cookie policy", policy(abpolicy.PolicyTypeCookie, &abpolicy.Backend{Name: "svc-a", Header: "v1"}), expected("cookie_X-Variant", v1)},
+		{"query policy", queryPolicy, expected("arg_exp", abPolicyLuaBackend{Upstream: "default-svc-b-http", Values: []string{"v2"}, Negate: true})},
+		{"method policy", policy(abpolicy.PolicyTypeMethod, &abpolicy.Backend{Name: "svc-b", Header: "post,put", Methods: []string{"POST", "PUT"}}),
+			expected("request_method", abPolicyLuaBackend{Upstream: "default-svc-b-http", Values: []string{"POST", "PUT"}})},
+		{"cidr policy", policy(abpolicy.PolicyTypeCIDR, &abpolicy.Backend{Name: "svc-b", Port: 8080, CIDRs: []string{"10.1.0.0/16", "2001:db8::/32"}}),
+			expected("binary_remote_addr", abPolicyLuaBackend{Upstream: "default-svc-b-8080", Values: []string{}, CIDRs: []abPolicyLuaCIDR{
+				{Network: []int{10, 1, 0, 0}, Bits: 16},
+				{Network: []int{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, Bits: 32},
+			}})},
+		{"sticky weight policy", stickyPolicy, stickyExpected},
+		{"percentage policy", percentagePolicy, percentageExpected},
+		{"unknown backend", policy(abpolicy.PolicyTypeHeader, &abpolicy.Backend{Name: "svc-x", Header: "v1"}, &abpolicy.Backend{Name: "svc-a", Header: "v1"}), expected("http_x_variant", v1)},
+		{"disabled policy", disabledPolicy, nil},
+		{"policy of another path", otherPathPolicy, nil},
+		{"mirror policy", mirrorPolicy, mirrorExpected},
+		{"mirror policy with unknown mirror backend", abpolicy.Config{Enabled: true, Host: "foo.bar.com", Type: abpolicy.PolicyTypeMirror, Path: "/cat", MirrorBackend: "svc-x"}, nil},
+	}
+
+	for _, test := range tests {
+		loc := &ingress.Location{
+			Path:     "/cat",
+			Ingress:  ing,
+			ABPolicy: test.policy,
+		}
+
+		str := buildABPolicy(loc)
+		if test.expected == nil {
+			if str != "" {
+				t.Errorf("%v: expected '' but returned '%v'", test.title, str)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(str, "[[") || !strings.HasSuffix(str, "]]") {
+			t.Errorf("%v: expected a Lua long string but returned '%v'", test.title, str)
+			continue
+		}
+
+		lp := &abPolicyLua{}
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(str[2:len(str)-2]), lp); err != nil {
+			t.Errorf("%v: unexpected error decoding '%v': %v", test.title, str, err)
+			continue
+		}
+		if !reflect.DeepEqual(lp, test.expected) {
+			t.Errorf("%v: expected '%+v' but returned '%+v'", test.title, test.expected, lp)
+		}
+	}
+}
+
+func TestLuaLongString(t *testing.T) {
+	tests := map[string]string{
+		`{"a":"b"}`:            `[[{"a":"b"}]]`,
+		`{"a":"[[x]]"}`:        `[=[{"a":"[[x]]"}]=]`,
+		`{"a":"]]","b":"]=]"}`: `[==[{"a":"]]","b":"]=]"}]==]`,
+	}
+
+	for s, expected := range tests {
+		if str := luaLongString(s); str != expected {
+			t.Errorf("expected '%v' but returned '%v'", expected, str)
+		}
+	}
+}
@@ -94,11 +94,40 @@ func IsMissingAnnotations(e error) bool {
 	return e == ErrMissingAnnotations
 }
 
-// IsInvalidContent checks if the err is an error which
-// indicates an annotations value is not valid
+// IsInvalidContent checks if the err is an error, or wraps an error,
+// which indicates an annotations value is not valid
 func IsInvalidContent(e error) bool {
-	_, ok := e.(InvalidContent)
-	return ok
+	for e != nil {
+		switch e.(type) {
+		case InvalidContent:
+			return true
+		}
+		e = unwrap(e)
+	}
+	return false
+}
+
+// Is reports whether any error in the chain of err matches target
+func Is(err, target error) bool {
+	for err != nil {
+		if err == target {
+			return true
+		}
+		if i, ok := err.(interface{ Is(error) bool }); ok && i.Is(target) {
+			return true
+		}
+		err = unwrap(err)
+	}
+	return false
+}
+
+// unwrap returns the error wrapped by err, or nil if it does not wrap an error
+func unwrap(err error) error {
+	w, ok := err.(interface{ Unwrap() error })
+	if !ok {
+		return nil
+	}
+	return w.Unwrap()
 }
 
 // New returns a new error
@@ -16,7 +16,22 @@ limitations under the License.
 
 package errors
 
-import "testing"
+import (
+	"testing"
+)
+
+// wrapped is an error wrapping another one
+type wrapped struct {
+	err error
+}
+
+func (w wrapped) Error() string {
+	return "wrapped: " + w.err.Error()
+}
+
+func (w wrapped) Unwrap() error {
+	return w.err
+}
 
 func TestIsLocationDenied(t *testing.T) {
 	err := NewLocationDenied("demo")
@@ -45,8 +60,21 @@ func TestInvalidContent(t *testing.T) {
 	if IsInvalidContent(nil) {
 		t.Error("expected false")
 	}
+	if !IsInvalidContent(wrapped{err}) {
+		t.Error("expected true")
+	}
 	err = NewLocationDenied("demo")
 	if IsInvalidContent(err) {
 		t.Error("expected false")
 	}
 }
+
+func TestIs(t *testing.T) {
+	err := wrapped{ErrMissingAnnotations}
+	if !Is(err, ErrMissingAnnotations) {
+		t.Error("expected true")
+	}
+	if Is(err, ErrInvalidAnnotationName) {
+		t.Error("expected false")
+	}
+}
@@ -422,6 +422,10 @@ func (l1 *Location) Equal(l2 *Location) bool {
 		return false
 	}
 
+	if !(&l1.ABPolicy).Equal(&l2.ABPolicy) {
+		return false
+	}
+
 	return true
 }
 
@@ -0,0 +1,259 @@
+local cjson = require("cjson.safe")
+local bit = require("bit")
+
+local string_byte = string.byte
+local string_format = string.format
+local string_lower = string.lower
+local string_match = string.match
+local string_sub = string.sub
+
+-- number of buckets split between the backends of weight and percentage policies
+local BUCKETS = 100
+
+local _M = {}
+
+-- policies of the locations, decoded once per worker and indexed by their JSON encoding
+local policies = {}
+
+local function get_policy(data)
+  local policy = policies[data]
+  if policy then
+    return policy
+  end
+
+  local err
+  policy, err = cjson.decode(data)
+  if not policy then
+    ngx.log(ngx.ERR, "error while decoding abpolicy: " .. tostring(err))
+    return nil
+  end
+
+  policies[data] = policy
+  return policy
+end
+
+local function has_prefix(s, prefix)
+  return string_sub(s, 1, #prefix) == prefix
+end
+
+local function has_suffix(s, suffix)
+  return #s > #suffix and string_sub(s, -#suffix) == suffix
+end
+
+local function matches_host(policy)
+  local host
+  if policy.sni then
+    host = ngx.var.ssl_server_name
+  else
+    host = ngx.var.host
+  end
+  if not host then
+    return false
+  end
+
+  host = string_lower(host)
+  if has_prefix(policy.host, "*.") then
+    return has_suffix(host, string_sub(policy.host, 2))
+  end
+
+  return host == policy.host
+end
+
+local function is_excluded(policy, uri)
+  for _, path in ipairs(policy.excludePaths) do
+    if has_prefix(uri, path) then
+      return true
+    end
+  end
+
+  return false
+end
+
+-- get_hash returns the hash of the key of sticky policies, or nil when the policy is not sticky
+local function get_hash(policy)
+  if policy.hashBy == "" then
+    return nil
+  end
+
+  local key = ngx.var[policy.hashBy] or ""
+  return ngx.crc32_long(key)
+end
+
+-- sticky policies sample the requests by the hash of their key, so a client is always
+-- in or out of the sample. The sample uses the digits of the hash above the bucket,
+-- so it does not change the split of the sampled requests between the backends.
+local function is_sampled(policy)
+  if policy.sample >= 100 then
+    return true
+  end
+
+  local hash = get_hash(policy)
+  if hash then
+    return math.floor(hash / BUCKETS) % 10000 < policy.sample * 100
+  end
+
+  return math.random() * 100 < policy.sample
+end
+
+-- addr is the binary client address, 4 bytes for IPv4 and 16 for IPv6
+local function in_cidr(addr, cidr)
+  local network = cidr.network
+  if #addr ~= #network then
+    return false
+  end
+
+  local bits = cidr.bits
+  for i = 1, #network do
+    if bits <= 0 then
+      return true
+    end
+
+    local mask = 0xff
+    if bits < 8 then
+      mask = bit.band(bit.lshift(0xff, 8 - bits), 0xff)
+    end
+    if bit.band(string_byte(addr, i), mask) ~= network[i] then
+      return false
+    end
+
+    bits = bits - 8
+  end
+
+  return true
+end
+
+local function matches_values(policy, backend, value)
+  if backend.presenceOnly then
+    return value ~= ""
+  end
+
+  if backend.cidrs then
+    for _, cidr in ipairs(backend.cidrs) do
+      if in_cidr(value, cidr) then
+        return true
+      end
+    end
+    return false
+  end
+
+  for _, v in ipairs(backend.values) do
+    if policy.match == "regex" then
+      if ngx.re.find(value, v, "jo") then
+        return true
+      end
+    elseif v == value then
+      return true
+    end
+  end
+
+  return false
+end
+
+local function get_bucket(policy)
+  local hash = get_hash(policy)
+  if hash then
+    return hash % BUCKETS
+  end
+
+  return math.random(0, BUCKETS - 1)
+end
+
+-- the backends are rendered in the order they must be evaluated,
+-- so the first one matching the request is selected
+local function select_backend(policy, uri)
+  if policy.split then
+    local bucket = get_bucket(policy)
+    for _, backend in ipairs(policy.backends) do
+      if bucket >= backend.from and bucket <= backend.to then
+        return backend
+      end
+    end
+    return nil
+  end
+
+  local value = ngx.var[policy.variable] or ""
+  for _, backend in ipairs(policy.backends) do
+    if not backend.path or has_prefix(uri, backend.path) then
+      local matches = matches_values(policy, backend, value)
+      if backend.negate then
+        matches = not matches
+      end
+      if matches then
+        return backend
+      end
+    end
+  end
+
+  return nil
+end
+
+-- rewrite selects the upstream of the request using the policy of the location. It must
+-- run before balancer.rewrite(), which picks the balancer of $proxy_upstream_name.
+function _M.rewrite(data)
+  local policy = get_policy(data)
+  if not policy then
+    return
+  end
+
+  local uri = ngx.var.uri
+  if not matches_host(policy) or is_excluded(policy, uri) or not is_sampled(policy) then
+    return
+  end
+
+  local backend = select_backend(policy, uri)
+  local upstream = policy.defaultUpstream
+  if backend then
+    upstream = backend.upstream
+  end
+  if upstream == "" then
+    return
+  end
+
+  ngx.var.abpolicy_upstream = upstream
+
+  if policy.dryRun then
+    ngx.log(ngx.NOTICE, string_format("abpolicy dry-run: upstream %s selected for %s%s",
+      upstream, ngx.var.host, ngx.var.request_uri))
+    return
+  end
+
+  ngx.var.proxy_upstream_name = upstream
+
+  if backend and backend.setHeaders then
+    for name, value in pairs(backend.setHeaders) do
+      ngx.req.set_header(name, value)
+    end
+  end
+end
+
+-- mirror runs in the subrequest copying the request to the mirror backend of the policy
+-- and ends it, so the copy is not sent, when the policy does not apply to the request
+function _M.mirror(data)
+  local policy = get_policy(data)
+  if not policy then
+    return ngx.exit(ngx.HTTP_NO_CONTENT)
+  end
+
+  -- the subrequest keeps the request line of the original request
+  local uri = string_match(ngx.var.request_uri, "^[^?]*")
+  if not matches_host(policy) or is_excluded(policy, uri) or not is_sampled(policy) then
+    return ngx.exit(ngx.HTTP_NO_CONTENT)
+  end
+
+  ngx.var.abpolicy_upstream = policy.mirrorUpstream
+
+  if policy.dryRun then
+    ngx.log(ngx.NOTICE, string_format("abpolicy dry-run: request %s%s would be mirrored to upstream %s",
+      ngx.var.host, ngx.var.request_uri, policy.mirrorUpstream))
+    return ngx.exit(ngx.HTTP_NO_CONTENT)
+  end
+end
+
+if _TEST then
+  _M.in_cidr = in_cidr
+  _M.is_sampled = is_sampled
+  _M.matches_host = matches_host
+  _M.select_backend = select_backend
+end
+
+return _M
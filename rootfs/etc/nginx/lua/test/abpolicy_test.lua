@@ -0,0 +1,266 @@
+_G._TEST = true
+
+local cjson = require("cjson")
+
+local original_ngx = ngx
+local function reset_ngx()
+  _G.ngx = original_ngx
+end
+
+local function mock_ngx(mock)
+  local _ngx = mock
+  setmetatable(_ngx, { __index = ngx })
+  _G.ngx = _ngx
+end
+
+local function header_policy(overrides)
+  local policy = {
+    host = "foo.bar.com",
+    sni = false,
+    split = false,
+    variable = "http_x_variant",
+    match = "exact",
+    sample = 100,
+    hashBy = "",
+    dryRun = false,
+    excludePaths = { "/cat/health" },
+    defaultUpstream = "",
+    backends = {
+      { upstream = "default-svc-a-80", values = { "v1" }, presenceOnly = false, negate = false, from = 0, to = 0 },
+      { upstream = "default-svc-b-80", values = { "v2", "beta" }, presenceOnly = false, negate = false, from = 0, to = 0,
+        setHeaders = { ["x-variant"] = "b" } },
+    },
+  }
+  for k, v in pairs(overrides or {}) do
+    policy[k] = v
+  end
+  return cjson.encode(policy)
+end
+
+describe("abpolicy", function()
+  local abpolicy
+  local set_header
+
+  before_each(function()
+    package.loaded["abpolicy"] = nil
+    abpolicy = require("abpolicy")
+  end)
+
+  after_each(function()
+    reset_ngx()
+  end)
+
+  local function request(var)
+    set_header = spy.new(function() end)
+    var.proxy_upstream_name = var.proxy_upstream_name or "default-svc-a-80"
+    var.abpolicy_upstream = ""
+    var.uri = var.uri or "/cat"
+    var.request_uri = var.request_uri or var.uri
+    var.host = var.host or "foo.bar.com"
+    mock_ngx({ var = var, req = { set_header = set_header } })
+    return var
+  end
+
+  describe("rewrite()", function()
+    it("routes the request to the backend matching the header", function()
+      local var = request({ http_x_variant = "beta" })
+      abpolicy.rewrite(header_policy())
+      assert.equal("default-svc-b-80", var.proxy_upstream_name)
+      assert.equal("default-svc-b-80", var.abpolicy_upstream)
+      assert.spy(set_header).was_called_with("x-variant", "b")
+    end)
+
+    it("keeps the upstream of the location when no backend matches", function()
+      local var = request({ http_x_variant = "v3" })
+      abpolicy.rewrite(header_policy())
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+      assert.equal("", var.abpolicy_upstream)
+    end)
+
+    it("routes the requests not matching any backend to the default backend", function()
+      local var = request({ http_x_variant = "v3" })
+      abpolicy.rewrite(header_policy({ defaultUpstream = "default-svc-c-80" }))
+      assert.equal("default-svc-c-80", var.proxy_upstream_name)
+    end)
+
+    it("ignores the requests of other hosts", function()
+      local var = request({ host = "bar.foo.com", http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy())
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+    end)
+
+    it("compares wildcard hosts with the subdomains of the request", function()
+      local var = request({ host = "www.bar.com", http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy({ host = "*.bar.com" }))
+      assert.equal("default-svc-b-80", var.proxy_upstream_name)
+    end)
+
+    it("compares the host with the TLS server name", function()
+      local var = request({ host = "foo.bar.com", ssl_server_name = "bar.foo.com", http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy({ sni = true }))
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+    end)
+
+    it("ignores the excluded paths", function()
+      local var = request({ uri = "/cat/health/live", http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy())
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+    end)
+
+    it("only logs the selected upstream in dry-run mode", function()
+      local var = request({ http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy({ dryRun = true }))
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+      assert.equal("default-svc-b-80", var.abpolicy_upstream)
+      assert.spy(set_header).was_not_called()
+    end)
+
+    it("does not evaluate the requests out of the sample", function()
+      local var = request({ http_x_variant = "v2" })
+      abpolicy.rewrite(header_policy({ sample = 0 }))
+      assert.equal("default-svc-a-80", var.proxy_upstream_name)
+    end)
+
+    it("splits the requests by bucket", function()
+      local policy = header_policy({
+        split = true,
+        hashBy = "remote_addr",
+        backends = {
+          { upstream = "default-svc-a-80", values = {}, presenceOnly = false, negate = false, from = 0, to = 49 },
+          { upstream = "default-svc-b-80", values = {}, presenceOnly = false, negate = false, from = 50, to = 99 },
+        },
+      })
+
+      local var = request({ remote_addr = "10.0.0.1" })
+      abpolicy.rewrite(policy)
+      local first = var.proxy_upstream_name
+
+      var = request({ remote_addr = "10.0.0.1" })
+      abpolicy.rewrite(policy)
+      assert.equal(first, var.proxy_upstream_name)
+      assert.equal(first, var.abpolicy_upstream)
+    end)
+  end)
+
+  describe("mirror()", function()
+    local exit
+
+    local function mirror_request(var)
+      var = request(var)
+      exit = spy.new(function() end)
+      ngx.exit = exit
+      return var
+    end
+
+    local function mirror_policy(overrides)
+      local policy = { mirrorUpstream = "default-svc-b-80", backends = {} }
+      for k, v in pairs(overrides or {}) do
+        policy[k] = v
+      end
+      return header_policy(policy)
+    end
+
+    it("sends the copy of the requests of the host of the policy", function()
+      local var = mirror_request({ uri = "/_abpolicy-mirror-L2NhdA", request_uri = "/cat?id=1" })
+      abpolicy.mirror(mirror_policy())
+      assert.spy(exit).was_not_called()
+      assert.equal("default-svc-b-80", var.abpolicy_upstream)
+    end)
+
+    it("does not send the copy of the requests of other hosts", function()
+      mirror_request({ host = "bar.foo.com", request_uri = "/cat" })
+      abpolicy.mirror(mirror_policy())
+      assert.spy(exit).was_called_with(ngx.HTTP_NO_CONTENT)
+    end)
+
+    it("does not send the copy of the requests of excluded paths", function()
+      mirror_request({ uri = "/_abpolicy-mirror-L2NhdA", request_uri = "/cat/health?full=1" })
+      abpolicy.mirror(mirror_policy())
+      assert.spy(exit).was_called_with(ngx.HTTP_NO_CONTENT)
+    end)
+
+    it("does not send the copy of the requests in dry-run mode", function()
+      local var = mirror_request({ request_uri = "/cat" })
+      abpolicy.mirror(mirror_policy({ dryRun = true }))
+      assert.spy(exit).was_called_with(ngx.HTTP_NO_CONTENT)
+      assert.equal("default-svc-b-80", var.abpolicy_upstream)
+    end)
+  end)
+
+  describe("is_sampled()", function()
+    it("samples the requests with the same key of sticky policies the same way", function()
+      local policy = cjson.decode(header_policy({ sample = 50, hashBy = "remote_addr" }))
+      for _, addr in ipairs({ "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4" }) do
+        request({ remote_addr = addr })
+        local sampled = abpolicy.is_sampled(policy)
+        for _ = 1, 10 do
+          assert.equal(sampled, abpolicy.is_sampled(policy))
+        end
+      end
+    end)
+
+    it("uses the hash of the key of sticky policies", function()
+      request({ remote_addr = "10.0.0.1" })
+      local hash = ngx.crc32_long("10.0.0.1")
+      local sample = math.floor(hash / 100) % 10000 / 100
+      local policy = cjson.decode(header_policy({ sample = sample - 0.005, hashBy = "remote_addr" }))
+      assert.is_false(abpolicy.is_sampled(policy))
+      policy.sample = sample + 0.005
+      assert.is_true(abpolicy.is_sampled(policy))
+    end)
+  end)
+
+  describe("select_backend()", function()
+    it("selects the backends not matching negated values", function()
+      request({ http_x_variant = "v1" })
+      local policy = cjson.decode(header_policy({
+        backends = { { upstream = "default-svc-b-80", values = { "v2" }, presenceOnly = false, negate = true, from = 0, to = 0 } },
+      }))
+      assert.equal("default-svc-b-80", abpolicy.select_backend(policy, "/cat").upstream)
+    end)
+
+    it("selects the backends of the path of the request", function()
+      request({ http_x_variant = "v2" })
+      local policy = cjson.decode(header_policy({
+        backends = { { upstream = "default-svc-b-80", values = { "v2" }, presenceOnly = false, negate = false, path = "/cat/api", from = 0, to = 0 } },
+      }))
+      assert.is_nil(abpolicy.select_backend(policy, "/cat"))
+      assert.equal("default-svc-b-80", abpolicy.select_backend(policy, "/cat/api/v1").upstream)
+    end)
+
+    it("selects the backends matching the presence of the header", function()
+      request({ http_x_variant = "anything" })
+      local policy = cjson.decode(header_policy({
+        backends = { { upstream = "default-svc-b-80", values = {}, presenceOnly = true, negate = false, from = 0, to = 0 } },
+      }))
+      assert.equal("default-svc-b-80", abpolicy.select_backend(policy, "/cat").upstream)
+    end)
+
+    it("compares regular expressions", function()
+      request({ http_x_variant = "v2-beta" })
+      local policy = cjson.decode(header_policy({ match = "regex" }))
+      policy.backends[1].values = { "^v2" }
+      assert.equal("default-svc-a-80", abpolicy.select_backend(policy, "/cat").upstream)
+    end)
+  end)
+
+  describe("in_cidr()", function()
+    it("matches IPv4 addresses", function()
+      local cidr = { network = { 10, 1, 0, 0 }, bits = 16 }
+      assert.is_true(abpolicy.in_cidr(string.char(10, 1, 200, 3), cidr))
+      assert.is_false(abpolicy.in_cidr(string.char(10, 2, 0, 1), cidr))
+    end)
+
+    it("matches prefixes not aligned to bytes", function()
+      local cidr = { network = { 192, 168, 1, 128 }, bits = 25 }
+      assert.is_true(abpolicy.in_cidr(string.char(192, 168, 1, 200), cidr))
+      assert.is_false(abpolicy.in_cidr(string.char(192, 168, 1, 100), cidr))
+    end)
+
+    it("matches IPv6 addresses", function()
+      local cidr = { network = { 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0 }, bits = 32 }
+      assert.is_true(abpolicy.in_cidr(string.char(0x20, 0x01, 0x0d, 0xb8, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12), cidr))
+      assert.is_false(abpolicy.in_cidr(string.char(10, 1, 0, 1), cidr))
+    end)
+  end)
+end)
@@ -0,0 +1,349 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package annotations
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Annotations - abpolicy", func() {
+	f := framework.NewDefaultFramework("abpolicy")
+	host := "abpolicy.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should generate a valid nginx configuration", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "x-version",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","header":"v2"}]`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		err := f.NginxConfigTest()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should rebuild the same server after the controller restarts", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "x-version",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","header":"v2"}]`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		var before string
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				before = server
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		err := f.RestartController()
+		Expect(err).NotTo(HaveOccurred())
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(Equal(before))
+			})
+	})
+
+	It("should configure the backends of the policy", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "x-version",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","header":"v2"}]`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		backends, err := f.GetControllerBackends()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backends).Should(ContainSubstring(fmt.Sprintf(`"name":"%v-http-svc-80"`, f.Namespace)))
+	})
+
+	It("should reach every backend of a weight policy", func() {
+		err := f.NewEchoDeploymentWithName("http-svc-canary", "http-svc-canary")
+		Expect(err).NotTo(HaveOccurred())
+
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "weight",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","weight":50},{"name":"http-svc-canary","weight":50}]`,
+		}
+
+		ing := newABPolicyIngress(host, f.Namespace, "http-svc", "http-svc-canary", annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		pods, err := f.KubeClientSet.CoreV1().Pods(f.Namespace).List(metav1.ListOptions{
+			LabelSelector: "app=http-svc",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods.Items).Should(HaveLen(1))
+
+		f.ExpectBackendReached(framework.HTTP, host, "/", nil, fmt.Sprintf("Hostname: %v", pods.Items[0].Name), 50)
+		f.ExpectBackendReached(framework.HTTP, host, "/", nil, "Hostname: http-svc-canary", 50)
+	})
+
+	It("should route the requests with the cookie of a cookie policy to its backend", func() {
+		err := f.NewEchoDeploymentWithName("http-svc-canary", "http-svc-canary")
+		Expect(err).NotTo(HaveOccurred())
+
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "cookie",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "variant",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc-canary","header":"v2"}]`,
+		}
+
+		ing := newABPolicyIngress(host, f.Namespace, "http-svc", "http-svc-canary", annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		for i := 0; i < 10; i++ {
+			_, _, body, err := f.Request(framework.HTTP, host, "/", map[string]string{"Cookie": "variant=v2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body).Should(ContainSubstring("Hostname: http-svc-canary"))
+
+			_, _, body, err = f.Request(framework.HTTP, host, "/", map[string]string{"Cookie": "variant=v1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body).ShouldNot(ContainSubstring("Hostname: http-svc-canary"))
+		}
+	})
+
+	It("should record the events of an ingress with an invalid annotation", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		err := wait.Poll(framework.Poll, time.Minute, func() (bool, error) {
+			events, err := f.GetEvents()
+			if err != nil {
+				return false, err
+			}
+
+			for _, e := range events {
+				// the store also records a Normal CREATE event for the ingress
+				if e.InvolvedObject.Kind == "Ingress" && e.InvolvedObject.Name == host &&
+					e.Type == corev1.EventTypeWarning && e.Reason == "InvalidAnnotationContent" {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		Expect(err).NotTo(HaveOccurred(), "expected an InvalidAnnotationContent warning event for ingress %v", host)
+	})
+
+	It("should accept a valid policy without warning events", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "x-version",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","header":"v2"}]`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		err := f.ExpectNoWarningEvents(host, 10*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should record a warning event for malformed backends", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		err := f.ExpectNoWarningEvents(host, time.Minute)
+		Expect(err).To(HaveOccurred(), "expected a warning event for ingress %v", host)
+		Expect(err.Error()).Should(ContainSubstring("InvalidAnnotationContent"))
+	})
+
+	It("should record a warning event for a disabled policy without backends", func() {
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":        "false",
+			"nginx.ingress.kubernetes.io/abpolicy-host":   host,
+			"nginx.ingress.kubernetes.io/abpolicy-type":   "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header": "x-version",
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		err := wait.Poll(framework.Poll, time.Minute, func() (bool, error) {
+			events, err := f.GetEvents()
+			if err != nil {
+				return false, err
+			}
+
+			for _, e := range events {
+				if e.InvolvedObject.Kind == "Ingress" && e.InvolvedObject.Name == host &&
+					e.Type == corev1.EventTypeWarning && e.Reason == "ABPolicyWarning" {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		Expect(err).NotTo(HaveOccurred(), "expected an ABPolicyWarning warning event for ingress %v", host)
+	})
+
+	It("should tell which backend of a header policy served the request", func() {
+		err := f.NewEchoDeploymentWithName("marker-a", "backend-marker-a")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = f.NewEchoDeploymentWithName("marker-b", "backend-marker-b")
+		Expect(err).NotTo(HaveOccurred())
+
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "header",
+			"nginx.ingress.kubernetes.io/abpolicy-header":   "x-backend",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"marker-b","header":"b"}]`,
+		}
+
+		ing := newABPolicyIngress(host, f.Namespace, "marker-a", "marker-b", annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		_, _, body, err := f.Request(framework.HTTP, host, "/", map[string]string{"x-backend": "b"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).Should(ContainSubstring("backend-marker-b"))
+
+		_, _, body, err = f.Request(framework.HTTP, host, "/", map[string]string{"x-backend": "a"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).Should(ContainSubstring("backend-marker-a"))
+	})
+
+	It("should keep the upstreams of a two backend abpolicy ingress", func() {
+		err := f.NewEchoDeploymentWithName("http-svc-canary", "http-svc-canary")
+		Expect(err).NotTo(HaveOccurred())
+
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "weight",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","weight":80},{"name":"http-svc-canary","weight":20}]`,
+		}
+
+		ing := newABPolicyIngress(host, f.Namespace, "http-svc", "http-svc-canary", annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		upstreams, err := f.GetNginxUpstreams()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(upstreams).Should(ContainElement("upstream_balancer"))
+
+		backends, err := f.GetControllerBackends()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backends).Should(ContainSubstring(fmt.Sprintf(`"name":"%v-http-svc-80"`, f.Namespace)))
+		Expect(backends).Should(ContainSubstring(fmt.Sprintf(`"name":"%v-http-svc-canary-80"`, f.Namespace)))
+	})
+})
+
+// newABPolicyIngress returns an ingress serving / with service and /canary with canary, so both
+// services have an upstream and can be backends of the policy of the ingress.
+func newABPolicyIngress(host, ns, service, canary string, annotations map[string]string) *extensions.Ingress {
+	ing := framework.NewSingleIngress(host, "/", host, ns, service, 80, &annotations)
+	ing.Spec.Rules[0].HTTP.Paths = append(ing.Spec.Rules[0].HTTP.Paths, extensions.HTTPIngressPath{
+		Path: "/canary",
+		Backend: extensions.IngressBackend{
+			ServiceName: canary,
+			ServicePort: intstr.FromInt(80),
+		},
+	})
+
+	return ing
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package annotations
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/parnurzeal/gorequest"
 
+	extensions "k8s.io/api/extensions/v1beta1"
+
 	"k8s.io/ingress-nginx/test/e2e/framework"
 )
 
@@ -79,6 +83,53 @@ var _ = framework.IngressNginxDescribe("Annotations - CORS", func() {
 		Expect(resp.StatusCode).Should(Equal(http.StatusNoContent))
 	})
 
+	It("should disable cors when the annotation changes", func() {
+		host := "cors.foo.com"
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/enable-cors": "true",
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring("more_set_headers 'Access-Control-Allow-Origin: *';"))
+			})
+
+		f.UpdateIngressAnnotation(host, map[string]string{"nginx.ingress.kubernetes.io/enable-cors": "false"},
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host))) &&
+					Expect(server).ShouldNot(ContainSubstring("Access-Control-Allow-Origin"))
+			})
+	})
+
+	It("should remove cors directives when the annotation is removed", func() {
+		host := "cors.foo.com"
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/enable-cors": "true",
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring("more_set_headers 'Access-Control-Allow-Origin: *';"))
+			})
+
+		_, err := f.UpdateIngress(host, func(ing *extensions.Ingress) error {
+			delete(ing.Annotations, "nginx.ingress.kubernetes.io/enable-cors")
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		f.WaitForNginxServerNotMatching(host,
+			func(server string) bool {
+				return strings.Contains(server, "Access-Control-Allow-Origin")
+			})
+	})
+
 	It("should set cors methods to only allow POST, GET", func() {
 		host := "cors.foo.com"
 		annotations := map[string]string{
@@ -56,5 +56,30 @@ var _ = framework.IngressNginxDescribe("Annotations - grpc", func() {
 						Expect(server).ShouldNot(ContainSubstring("proxy_pass"))
 				})
 		})
+
+		It("should proxy unary calls to the gRPC backend", func() {
+			host := "grpc"
+
+			annotations := map[string]string{
+				"nginx.ingress.kubernetes.io/backend-protocol": "GRPC",
+			}
+
+			ing := framework.NewSingleIngressWithTLS(host, "/", host, f.IngressController.Namespace, "fortune-teller", 50051, &annotations)
+			f.EnsureIngress(ing)
+
+			f.WaitForNginxServer(host,
+				func(server string) bool {
+					return Expect(server).Should(ContainSubstring("grpc_pass"))
+				})
+
+			conn, err := f.GRPCDial(host)
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			// an empty message is a valid PredictionRequest
+			reply, err := framework.GRPCUnaryCall(conn, "/build.stack.fortune.FortuneTeller/Predict", []byte{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reply).NotTo(BeEmpty())
+		})
 	})
 })
@@ -19,6 +19,9 @@ package annotations
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
 	"k8s.io/ingress-nginx/test/e2e/framework"
 )
 
@@ -47,4 +50,34 @@ var _ = framework.IngressNginxDescribe("Annotations - Configurationsnippet", fun
 				return Expect(server).Should(ContainSubstring(`more_set_headers "Request-Id: $req_id";`))
 			})
 	})
+
+	It("should update the snippet of an existing ingress", func() {
+		host := "configurationsnippet.foo.com"
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/configuration-snippet": `
+				more_set_headers "Request-Id: $req_id";`,
+		}
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(`more_set_headers "Request-Id: $req_id";`))
+			})
+
+		ing, err := f.UpdateIngress(host, func(ing *extensions.Ingress) error {
+			ing.Annotations["nginx.ingress.kubernetes.io/configuration-snippet"] = `
+				more_set_headers "Connection-Id: $connection";`
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ing.Annotations).Should(HaveKeyWithValue("nginx.ingress.kubernetes.io/configuration-snippet", ContainSubstring("Connection-Id")))
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(`more_set_headers "Connection-Id: $connection";`)) &&
+					Expect(server).ShouldNot(ContainSubstring("Request-Id"))
+			})
+	})
 })
@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - extra arguments", func() {
+	f := framework.NewDefaultFramework("controller-args")
+	f.ExtraArgs = []string{"--v=5"}
+
+	It("should pass the extra arguments to the controller container", func() {
+		l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).NotTo(BeEmpty())
+
+		for _, pod := range l.Items {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			Expect(pod.Spec.Containers[0].Args).Should(ContainElement("--v=5"))
+		}
+	})
+})
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - exec", func() {
+	f := framework.NewDefaultFramework("controller-exec")
+
+	It("should send stdin to the command", func() {
+		l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).NotTo(BeEmpty())
+
+		out, err := f.ExecCommandWithStdin(&l.Items[0], strings.NewReader("hello from stdin"), "cat")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).Should(Equal("hello from stdin"))
+	})
+
+	It("should send requests to NGINX from the controller pod", func() {
+		out, err := f.CurlInController("/", map[string]string{"Host": "curl.foo.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).Should(HavePrefix("HTTP/1.1 404 Not Found"))
+		Expect(out).Should(ContainSubstring("default backend - 404"))
+	})
+})
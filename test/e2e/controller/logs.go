@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - logs", func() {
+	f := framework.NewDefaultFramework("controller-logs")
+	host := "logs.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should log requests in the access log", func() {
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		status, _, _, err := f.Request(framework.HTTP, host, "/access-log-entry", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).Should(Equal(http.StatusOK))
+
+		err = f.WaitForNginxLogEntry("GET /access-log-entry", time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should stream the logs of the controller", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var out bytes.Buffer
+		err := f.StreamNginxLogs(ctx, &out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).Should(ContainSubstring("Starting NGINX Ingress controller"))
+	})
+})
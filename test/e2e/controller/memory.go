@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - memory", func() {
+	f := framework.NewDefaultFramework("controller-memory")
+
+	// upper bound of the memory of the controller pod after creating the ingresses
+	maxMemory := int64(512 * 1024 * 1024)
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should keep the memory of the controller bounded", func() {
+		_, err := f.GetControllerMemory()
+		if err == framework.ErrMetricsUnavailable {
+			Skip("resource metrics API is not available")
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 50; i++ {
+			host := fmt.Sprintf("memory-%v.foo.com", i)
+			f.EnsureIngress(framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, nil))
+		}
+
+		f.WaitForNginxServer("memory-49.foo.com",
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring("server_name memory-49.foo.com"))
+			})
+
+		memory, err := f.GetControllerMemory()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(memory).Should(BeNumerically("<", maxMemory))
+	})
+})
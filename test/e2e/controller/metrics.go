@@ -0,0 +1,40 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - metrics", func() {
+	f := framework.NewDefaultFramework("controller-metrics")
+
+	It("should expose the reload metrics", func() {
+		metrics, err := f.GetMetrics()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).Should(ContainSubstring("nginx_ingress_controller_success"))
+
+		count, err := f.GetMetricValue("nginx_ingress_controller_success", map[string]string{
+			"controller_namespace": f.IngressController.Namespace,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).Should(BeNumerically(">=", 1))
+	})
+})
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - port-forward", func() {
+	f := framework.NewDefaultFramework("controller-port-forward")
+
+	It("should forward a local port to the metrics port of the controller", func() {
+		l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).NotTo(BeEmpty())
+
+		port, stop, err := f.PortForward(&l.Items[0], 10254)
+		Expect(err).NotTo(HaveOccurred())
+		defer stop()
+
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%v/metrics", port))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+		Expect(string(body)).Should(ContainSubstring("nginx_ingress_controller"))
+	})
+})
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - reload", func() {
+	f := framework.NewDefaultFramework("controller-reload")
+	host := "reload.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should not reload when an ingress is updated without changes", func() {
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		f.ExpectNoReloadDuring(10*time.Second, func() {
+			f.EnsureIngress(framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, nil))
+		})
+	})
+
+	It("should settle after several ingresses are created", func() {
+		for i := 0; i < 5; i++ {
+			h := fmt.Sprintf("settle-%v.foo.com", i)
+			f.EnsureIngress(framework.NewSingleIngress(h, "/", h, f.Namespace, "http-svc", 80, nil))
+		}
+
+		err := f.WaitForReloadsToSettle(10*time.Second, 2*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		f.WaitForNginxConfiguration(
+			func(cfg string) bool {
+				for i := 0; i < 5; i++ {
+					if !strings.Contains(cfg, fmt.Sprintf("server_name settle-%v.foo.com", i)) {
+						return false
+					}
+				}
+				return true
+			})
+	})
+})
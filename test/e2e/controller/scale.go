@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Controller - scale", func() {
+	f := framework.NewDefaultFramework("controller-scale")
+
+	It("should scale the ingress controller up and down", func() {
+		err := f.ScaleControllerTo(2)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error scaling the ingress controller to 2 replicas")
+
+		d, err := f.KubeClientSet.AppsV1beta1().Deployments(f.IngressController.Namespace).Get("nginx-ingress-controller", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*d.Spec.Replicas).Should(BeNumerically("==", 2))
+
+		err = f.ScaleControllerTo(1)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error scaling the ingress controller to 1 replica")
+
+		d, err = f.KubeClientSet.AppsV1beta1().Deployments(f.IngressController.Namespace).Get("nginx-ingress-controller", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*d.Spec.Replicas).Should(BeNumerically("==", 1))
+	})
+})
@@ -17,12 +17,15 @@ limitations under the License.
 package framework
 
 import (
+	"fmt"
 	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -39,6 +42,29 @@ func (f *Framework) NewEchoDeploymentWithReplicas(replicas int32) {
 	f.NewDeployment("http-svc", "gcr.io/kubernetes-e2e-test-images/echoserver:2.1", 8080, replicas)
 }
 
+// NewEchoDeploymentWithNameAndReplicas creates a deployment of the echoserver image with
+// the given number of replicas and a service of the same name on port 80 in the framework
+// namespace. It returns once the service has an endpoint for each replica.
+func (f *Framework) NewEchoDeploymentWithNameAndReplicas(name string, replicas int) error {
+	if replicas < 1 {
+		return fmt.Errorf("invalid number of replicas %v", replicas)
+	}
+
+	return f.newDeployment(f.Namespace, name, "gcr.io/kubernetes-e2e-test-images/echoserver:2.1", 8080, int32(replicas), nil)
+}
+
+// NewEchoDeploymentWithName creates a single replica deployment of the echoserver image and a
+// service named serviceName on port 80 in the framework namespace. The echoserver prints the
+// POD_NAME environment variable in its response, which is set to marker so tests can tell
+// which backend served a request.
+func (f *Framework) NewEchoDeploymentWithName(serviceName, marker string) error {
+	env := []corev1.EnvVar{
+		{Name: "POD_NAME", Value: marker},
+	}
+
+	return f.newDeployment(f.Namespace, serviceName, "gcr.io/kubernetes-e2e-test-images/echoserver:2.1", 8080, 1, env)
+}
+
 // NewHttpbinDeployment creates a new single replica deployment of the httpbin image in a particular namespace.
 func (f *Framework) NewHttpbinDeployment() {
 	f.NewDeployment("httpbin", "kennethreitz/httpbin", 80, 1)
@@ -46,10 +72,26 @@ func (f *Framework) NewHttpbinDeployment() {
 
 // NewDeployment creates a new deployment in a particular namespace.
 func (f *Framework) NewDeployment(name, image string, port int32, replicas int32) {
+	f.NewDeploymentInNamespace(f.Namespace, name, image, port, replicas)
+}
+
+// NewDeploymentInNamespace creates a new deployment and a service with the same name in the given namespace.
+func (f *Framework) NewDeploymentInNamespace(namespace, name, image string, port int32, replicas int32) {
+	err := f.newDeployment(namespace, name, image, port, replicas, nil)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// newDeployment creates a deployment and a service with the same name in the given namespace
+// and waits until the service has an endpoint for each replica
+func (f *Framework) newDeployment(namespace, name, image string, port int32, replicas int32, env []corev1.EnvVar) error {
+	if env == nil {
+		env = []corev1.EnvVar{}
+	}
+
 	deployment := &extensions.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: f.IngressController.Namespace,
+			Namespace: namespace,
 		},
 		Spec: extensions.DeploymentSpec{
 			Replicas: NewInt32(replicas),
@@ -70,7 +112,7 @@ func (f *Framework) NewDeployment(name, image string, port int32, replicas int32
 						{
 							Name:  name,
 							Image: image,
-							Env:   []corev1.EnvVar{},
+							Env:   env,
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "http",
@@ -85,18 +127,21 @@ func (f *Framework) NewDeployment(name, image string, port int32, replicas int32
 	}
 
 	d, err := f.EnsureDeployment(deployment)
-	Expect(err).NotTo(HaveOccurred(), "failed to create a deployment")
-	Expect(d).NotTo(BeNil(), "expected a deployement but none returned")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a deployment")
+	}
 
-	err = WaitForPodsReady(f.KubeClientSet, 5*time.Minute, int(replicas), f.IngressController.Namespace, metav1.ListOptions{
+	err = WaitForPodsReady(f.KubeClientSet, 5*time.Minute, int(replicas), namespace, metav1.ListOptions{
 		LabelSelector: fields.SelectorFromSet(fields.Set(d.Spec.Template.ObjectMeta.Labels)).String(),
 	})
-	Expect(err).NotTo(HaveOccurred(), "failed to wait for to become ready")
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for to become ready")
+	}
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: f.IngressController.Namespace,
+			Namespace: namespace,
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
@@ -113,6 +158,95 @@ func (f *Framework) NewDeployment(name, image string, port int32, replicas int32
 		},
 	}
 
+	_, err = f.KubeClientSet.CoreV1().Services(namespace).Update(service)
+	if k8sErrors.IsNotFound(err) {
+		_, err = f.KubeClientSet.CoreV1().Services(namespace).Create(service)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create a service")
+	}
+
+	err = WaitForEndpointsInNamespace(f.KubeClientSet, namespace, name, int(replicas), 5*time.Minute)
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for endpoints to become ready")
+	}
+
+	return nil
+}
+
+// NewUDPEchoDeployment creates a new single replica deployment of a UDP server that
+// replies with the content of each datagram, exposed by the udp-echo service on port 9000
+func (f *Framework) NewUDPEchoDeployment() {
+	name := "udp-echo"
+
+	deployment := &extensions.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: f.Namespace,
+		},
+		Spec: extensions.DeploymentSpec{
+			Replicas: NewInt32(1),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					TerminationGracePeriodSeconds: NewInt64(0),
+					Containers: []corev1.Container{
+						{
+							Name:  name,
+							Image: "alpine/socat:1.0.3",
+							Args:  []string{"UDP4-RECVFROM:9000,fork", "EXEC:cat"},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "udp",
+									ContainerPort: 9000,
+									Protocol:      corev1.ProtocolUDP,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	d, err := f.EnsureDeployment(deployment)
+	Expect(err).NotTo(HaveOccurred(), "failed to create a deployment")
+	Expect(d).NotTo(BeNil(), "expected a deployement but none returned")
+
+	err = WaitForPodsReady(f.KubeClientSet, 5*time.Minute, 1, f.Namespace, metav1.ListOptions{
+		LabelSelector: fields.SelectorFromSet(fields.Set(d.Spec.Template.ObjectMeta.Labels)).String(),
+	})
+	Expect(err).NotTo(HaveOccurred(), "failed to wait for to become ready")
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: f.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "udp",
+					Port:       9000,
+					TargetPort: intstr.FromInt(9000),
+					Protocol:   corev1.ProtocolUDP,
+				},
+			},
+			Selector: map[string]string{
+				"app": name,
+			},
+		},
+	}
+
 	s := f.EnsureService(service)
 	Expect(s).NotTo(BeNil(), "expected a service but none returned")
 }
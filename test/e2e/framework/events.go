@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// GetEvents returns the events of the test namespace sorted by timestamp
+func (f *Framework) GetEvents() ([]v1.Event, error) {
+	l, err := f.KubeClientSet.CoreV1().Events(f.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	events := l.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+
+	return events, nil
+}
+
+// DumpEvents logs the events of the test namespace sorted by timestamp
+func (f *Framework) DumpEvents() {
+	By("Dumping namespace events after a failure running a test")
+	events, err := f.GetEvents()
+	if err != nil {
+		Logf("unexpected error listing events: %v", err)
+		return
+	}
+
+	for _, e := range events {
+		Logf("%v %v %v %v/%v: %v", eventTime(&e).Format(time.RFC3339), e.Type, e.Reason,
+			e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Message)
+	}
+}
+
+// ExpectNoWarningEvents checks the events of an Ingress of the test namespace
+// during the given duration and returns an error as soon as a Warning event
+// is found, i.e. because one of its annotations contains an invalid value
+func (f *Framework) ExpectNoWarningEvents(ingressName string, within time.Duration) error {
+	err := wait.PollImmediate(Poll, within, func() (bool, error) {
+		events, err := f.GetEvents()
+		if err != nil {
+			return false, err
+		}
+
+		for _, e := range events {
+			if e.Type == v1.EventTypeWarning && e.InvolvedObject.Kind == "Ingress" && e.InvolvedObject.Name == ingressName {
+				return false, fmt.Errorf("warning event %v in ingress %v: %v", e.Reason, ingressName, e.Message)
+			}
+		}
+
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil
+	}
+
+	return err
+}
+
+// eventTime returns the time of the last occurrence of an event
+func eventTime(e *v1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+
+	return e.FirstTimestamp.Time
+}
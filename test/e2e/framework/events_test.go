@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetEvents(t *testing.T) {
+	now := time.Now()
+
+	newEvent := func(name string, last time.Time) *v1.Event {
+		return &v1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+			},
+			LastTimestamp: metav1.NewTime(last),
+		}
+	}
+
+	f := &Framework{
+		Namespace: "test",
+		KubeClientSet: fake.NewSimpleClientset(
+			newEvent("c", now),
+			newEvent("a", now.Add(-2*time.Minute)),
+			newEvent("b", now.Add(-time.Minute)),
+		),
+	}
+
+	events, err := f.GetEvents()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"a", "b", "c"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v events, but %v were returned", len(expected), len(events))
+	}
+
+	for i, e := range events {
+		if e.Name != expected[i] {
+			t.Errorf("expected event \"%v\" at position %v, but \"%v\" was returned", expected[i], i, e.Name)
+		}
+	}
+}
+
+func TestExpectNoWarningEvents(t *testing.T) {
+	newEvent := func(name, eventType, kind, object string) *v1.Event {
+		return &v1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+			},
+			Type: eventType,
+			InvolvedObject: v1.ObjectReference{
+				Kind:      kind,
+				Name:      object,
+				Namespace: "test",
+			},
+			Reason:  "InvalidAnnotationContent",
+			Message: "invalid value",
+		}
+	}
+
+	tests := []struct {
+		title  string
+		events []*v1.Event
+		expErr bool
+	}{
+		{"without events", nil, false},
+		{"normal event", []*v1.Event{newEvent("a", v1.EventTypeNormal, "Ingress", "foo")}, false},
+		{"warning event of another ingress", []*v1.Event{newEvent("a", v1.EventTypeWarning, "Ingress", "bar")}, false},
+		{"warning event of a pod", []*v1.Event{newEvent("a", v1.EventTypeWarning, "Pod", "foo")}, false},
+		{"warning event", []*v1.Event{
+			newEvent("a", v1.EventTypeNormal, "Ingress", "foo"),
+			newEvent("b", v1.EventTypeWarning, "Ingress", "foo"),
+		}, true},
+	}
+
+	for _, test := range tests {
+		client := fake.NewSimpleClientset()
+		for _, e := range test.events {
+			_, err := client.CoreV1().Events("test").Create(e)
+			if err != nil {
+				t.Fatalf("%v: unexpected error creating event: %v", test.title, err)
+			}
+		}
+
+		f := &Framework{
+			Namespace:     "test",
+			KubeClientSet: client,
+		}
+
+		err := f.ExpectNoWarningEvents("foo", 100*time.Millisecond)
+		if test.expErr && err == nil {
+			t.Errorf("%v: expected an error but none was returned", test.title)
+		}
+		if !test.expErr && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+		}
+	}
+}
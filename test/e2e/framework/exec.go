@@ -17,15 +17,23 @@ limitations under the License.
 package framework
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
 )
 
 // ExecCommand executes a command inside a the first container in a running pod
@@ -52,20 +60,119 @@ func (f *Framework) ExecCommand(pod *v1.Pod, command string) (string, error) {
 	return execOut.String(), nil
 }
 
-// NewIngressController deploys a new NGINX Ingress controller in a namespace
-func (f *Framework) NewIngressController(namespace string) error {
+// ExecCommandWithStdin executes a command inside the first container in a running pod,
+// sending the content of stdin to the standard input of the command
+func (f *Framework) ExecCommandWithStdin(pod *v1.Pod, stdin io.Reader, args ...string) (string, error) {
+	var (
+		execOut bytes.Buffer
+		execErr bytes.Buffer
+	)
+
+	kubectlArgs := []string{"exec", "-i", "--namespace", pod.Namespace, pod.Name, "--container", "nginx-ingress-controller", "--"}
+	cmd := exec.Command(KubectlPath, append(kubectlArgs, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &execOut
+	cmd.Stderr = &execErr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("could not execute '%s %s': %v", cmd.Path, cmd.Args, err)
+	}
+
+	if execErr.Len() > 0 {
+		return "", fmt.Errorf("stderr: %v", execErr.String())
+	}
+
+	return execOut.String(), nil
+}
+
+// CurlInController sends a request to NGINX from inside the ingress controller pod
+// using curl against http://localhost:80 and returns the status line, the headers
+// and the body of the response. The server is selected using the Host header.
+func (f *Framework) CurlInController(path string, headers map[string]string) (string, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := []string{"curl", "-s", "-i"}
+	for _, k := range keys {
+		args = append(args, "-H", shellQuote(fmt.Sprintf("%v: %v", k, headers[k])))
+	}
+	args = append(args, shellQuote(fmt.Sprintf("http://localhost:80%v", path)))
+
+	return f.ExecCommand(pod, strings.Join(args, " "))
+}
+
+// shellQuote quotes s to be used as a single word in a bash command
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// NginxConfigTest runs nginx -t in the ingress controller pod and returns
+// an error containing the output of the command if the configuration is not valid
+func (f *Framework) NginxConfigTest() error {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(KubectlPath, "exec", "--namespace", pod.Namespace, pod.Name, "--container", "nginx-ingress-controller", "--",
+		"nginx", "-t", "-c", "/etc/nginx/nginx.conf")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid nginx configuration: %v\n%v", err, string(out))
+	}
+
+	return nil
+}
+
+// NewIngressController deploys a new NGINX Ingress controller in a namespace,
+// watching the Ingresses of watchNamespace. The flags in ExtraArgs are appended
+// to the arguments of the controller container.
+func (f *Framework) NewIngressController(namespace, watchNamespace string) error {
 	// Creates an nginx deployment
-	cmd := exec.Command("./wait-for-nginx.sh", namespace)
+	cmd := exec.Command("./wait-for-nginx.sh", namespace, watchNamespace)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("Unexpected error waiting for ingress controller deployment: %v.\nLogs:\n%v", err, string(out))
 	}
 
+	if len(f.ExtraArgs) == 0 {
+		return nil
+	}
+
+	deployment, err := f.KubeClientSet.AppsV1beta1().Deployments(namespace).Get("nginx-ingress-controller", metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	args := deployment.Spec.Template.Spec.Containers[0].Args
+	deployment.Spec.Template.Spec.Containers[0].Args = append(args, f.ExtraArgs...)
+	_, err = f.KubeClientSet.AppsV1beta1().Deployments(namespace).Update(deployment)
+	if err != nil {
+		return fmt.Errorf("Unexpected error adding arguments %v to the ingress controller: %v", f.ExtraArgs, err)
+	}
+
+	// wait until the pods using the new arguments replace the existing ones
+	cmd = exec.Command(KubectlPath, "rollout", "status", "--request-timeout=3m", "--namespace", namespace, "deployment", "nginx-ingress-controller")
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Unexpected error waiting for ingress controller deployment: %v.\nLogs:\n%v", err, string(out))
+	}
+
 	return nil
 }
 
 var (
-	proxyRegexp = regexp.MustCompile("Starting to serve on .*:([0-9]+)")
+	proxyRegexp       = regexp.MustCompile("Starting to serve on .*:([0-9]+)")
+	portForwardRegexp = regexp.MustCompile("Forwarding from 127.0.0.1:([0-9]+) ->")
 )
 
 // KubectlProxy creates a proxy to kubernetes apiserver
@@ -96,6 +203,73 @@ func (f *Framework) KubectlProxy(port int) (int, *exec.Cmd, error) {
 	return -1, cmd, fmt.Errorf("Failed to parse port from proxy stdout: %s", output)
 }
 
+// PortForward forwards a random local port to a port of a pod using the port-forward
+// subresource of the pod. Requests to localhost:localPort reach the pod until the
+// returned stop function is called.
+func (f *Framework) PortForward(pod *v1.Pod, podPort int) (int, func(), error) {
+	transport, upgrader, err := spdy.RoundTripperFor(f.KubeConfig)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	req := f.KubeClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+
+	// the forwarder only reports the random local port in its output
+	r, w := io.Pipe()
+	var errOut bytes.Buffer
+	fw, err := portforward.New(dialer, []string{fmt.Sprintf("0:%v", podPort)}, stopCh, readyCh, w, &errOut)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	go func() {
+		w.CloseWithError(fw.ForwardPorts())
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(stopCh)
+		})
+	}
+
+	localPort, err := readForwardedPort(r)
+	if err != nil {
+		stop()
+		return -1, nil, fmt.Errorf("unexpected error forwarding port %v of pod %v: %v\n%s", podPort, pod.Name, err, errOut.String())
+	}
+
+	// the output must be consumed so the forward is not blocked
+	go io.Copy(ioutil.Discard, r)
+
+	return localPort, stop, nil
+}
+
+// readForwardedPort returns the local port of the output of a port forward
+func readForwardedPort(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := portForwardRegexp.FindStringSubmatch(scanner.Text())
+		if len(match) == 2 {
+			return strconv.Atoi(match[1])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return -1, err
+	}
+
+	return -1, fmt.Errorf("port forward exited before forwarding a port")
+}
+
 func startCmdAndStreamOutput(cmd *exec.Cmd) (stdout, stderr io.ReadCloser, err error) {
 	stdout, err = cmd.StdoutPipe()
 	if err != nil {
@@ -0,0 +1,49 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadForwardedPort(t *testing.T) {
+	tests := []struct {
+		title    string
+		output   string
+		expected int
+		expErr   bool
+	}{
+		{"ipv4 and ipv6", "Forwarding from 127.0.0.1:37813 -> 10254\nForwarding from [::1]:37813 -> 10254\n", 37813, false},
+		{"ipv6 first", "Forwarding from [::1]:37813 -> 10254\nForwarding from 127.0.0.1:37813 -> 10254\n", 37813, false},
+		{"without forward", "error: unable to forward port because pod is not running\n", -1, true},
+		{"empty output", "", -1, true},
+	}
+
+	for _, test := range tests {
+		port, err := readForwardedPort(strings.NewReader(test.output))
+		if test.expErr && err == nil {
+			t.Errorf("%v: expected an error but none was returned", test.title)
+		}
+		if !test.expErr && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+		}
+		if port != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, port)
+		}
+	}
+}
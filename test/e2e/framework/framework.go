@@ -14,8 +14,12 @@ limitations under the License.
 package framework
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,10 +27,12 @@ import (
 	"k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	apiextcs "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 
@@ -35,6 +41,8 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/internal/ingress"
 )
 
 // RequestScheme define a scheme used in a test request.
@@ -57,6 +65,7 @@ type Framework struct {
 
 	// A Kubernetes and Service Catalog client
 	KubeClientSet          kubernetes.Interface
+	DynamicClient          dynamic.Interface
 	KubeConfig             *restclient.Config
 	APIExtensionsClientSet apiextcs.Interface
 
@@ -66,6 +75,28 @@ type Framework struct {
 	cleanupHandle CleanupActionHandle
 
 	IngressController *ingressController
+
+	// Namespace contains the objects created by the test. Unless
+	// SeparateControllerNamespace is set, it is the namespace of the ingress controller.
+	Namespace string
+
+	// SeparateControllerNamespace deploys the ingress controller in a namespace
+	// different from the one used by the test. It must be set before BeforeEach runs.
+	SeparateControllerNamespace bool
+
+	// ExtraArgs are appended to the arguments of the ingress controller container.
+	// It must be set before BeforeEach runs.
+	ExtraArgs []string
+
+	// RequestTimeout is the maximum duration of a single HTTP request sent
+	// by the request helpers. Defaults to 10 seconds.
+	RequestTimeout time.Duration
+
+	// objects created by ApplyManifest
+	manifestObjects []manifestObject
+
+	// namespaces created by the test in addition to the namespace of the ingress controller
+	namespaces []string
 }
 
 type ingressController struct {
@@ -79,7 +110,8 @@ type ingressController struct {
 // you (you can write additional before/after each functions).
 func NewDefaultFramework(baseName string) *Framework {
 	f := &Framework{
-		BaseName: baseName,
+		BaseName:       baseName,
+		RequestTimeout: defaultRequestTimeout,
 	}
 
 	BeforeEach(f.BeforeEach)
@@ -100,16 +132,25 @@ func (f *Framework) BeforeEach() {
 	f.KubeClientSet, err = kubernetes.NewForConfig(kubeConfig)
 	Expect(err).NotTo(HaveOccurred())
 
+	f.DynamicClient, err = dynamic.NewForConfig(kubeConfig)
+	Expect(err).NotTo(HaveOccurred())
+
 	By("Building a namespace api object")
-	ingressNamespace, err := CreateKubeNamespace(f.BaseName, f.KubeClientSet)
+	f.Namespace, err = CreateKubeNamespace(f.BaseName, f.KubeClientSet)
 	Expect(err).NotTo(HaveOccurred())
 
+	ingressNamespace := f.Namespace
+	if f.SeparateControllerNamespace {
+		ingressNamespace, err = CreateKubeNamespace(f.BaseName+"-controller", f.KubeClientSet)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
 	f.IngressController = &ingressController{
 		Namespace: ingressNamespace,
 	}
 
 	By("Starting new ingress controller")
-	err = f.NewIngressController(f.IngressController.Namespace)
+	err = f.NewIngressController(f.IngressController.Namespace, f.Namespace)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = WaitForPodsReady(f.KubeClientSet, 5*time.Minute, 1, f.IngressController.Namespace, metav1.ListOptions{
@@ -131,16 +172,82 @@ func (f *Framework) BeforeEach() {
 func (f *Framework) AfterEach() {
 	RemoveCleanupAction(f.cleanupHandle)
 
+	// the state must be collected before the namespace of the controller is removed
+	if CurrentGinkgoTestDescription().Failed {
+		f.dumpNginxState()
+		f.DumpEvents()
+	}
+
+	err := f.deleteManifestObjects()
+	Expect(err).NotTo(HaveOccurred())
+
 	By("Waiting for test namespace to no longer exist")
-	err := DeleteKubeNamespace(f.KubeClientSet, f.IngressController.Namespace)
+	err = DeleteKubeNamespace(f.KubeClientSet, f.IngressController.Namespace)
 	Expect(err).NotTo(HaveOccurred())
 
-	if CurrentGinkgoTestDescription().Failed {
-		log, err := f.NginxLogs()
-		Expect(err).ToNot(HaveOccurred())
-		By("Dumping NGINX logs after a failure running a test")
+	if f.Namespace != f.IngressController.Namespace {
+		err := DeleteKubeNamespace(f.KubeClientSet, f.Namespace)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	for _, ns := range f.namespaces {
+		err := DeleteKubeNamespace(f.KubeClientSet, ns)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error deleting namespace %v", ns)
+	}
+	f.namespaces = nil
+}
+
+// dumpNginxState logs the NGINX logs, the nginx-configuration ConfigMap and
+// nginx.conf. Errors are logged so one failing dump does not prevent the others.
+func (f *Framework) dumpNginxState() {
+	By("Dumping NGINX logs after a failure running a test")
+	log, err := f.NginxLogs()
+	if err != nil {
+		Logf("unexpected error reading NGINX logs: %v", err)
+	} else {
 		Logf("%v", log)
 	}
+
+	By("Dumping the NGINX configuration ConfigMap after a failure running a test")
+	data, err := f.GetNginxConfigMapData()
+	if err != nil {
+		Logf("unexpected error reading the NGINX configuration ConfigMap: %v", err)
+	} else {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			Logf("%v: %v", k, data[k])
+		}
+	}
+
+	By("Dumping nginx.conf after a failure running a test")
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		Logf("unexpected error reading nginx.conf: %v", err)
+		return
+	}
+
+	cfg, err := f.ExecCommand(pod, "cat /etc/nginx/nginx.conf")
+	if err != nil {
+		Logf("unexpected error reading nginx.conf: %v", err)
+		return
+	}
+	Logf("%v", cfg)
+}
+
+// CreateNamespace creates an additional namespace deleted after the test
+func (f *Framework) CreateNamespace(baseName string) (string, error) {
+	ns, err := CreateKubeNamespace(baseName, f.KubeClientSet)
+	if err != nil {
+		return "", err
+	}
+
+	f.namespaces = append(f.namespaces, ns)
+	return ns, nil
 }
 
 // IngressNginxDescribe wrapper function for ginkgo describe. Adds namespacing.
@@ -181,19 +288,65 @@ func (f *Framework) GetNginxURL(scheme RequestScheme) string {
 	port, err := f.GetNginxPort(fmt.Sprintf("%v", scheme))
 	Expect(err).NotTo(HaveOccurred(), "unexpected error obtaning NGINX Port")
 
-	return fmt.Sprintf("%v://%v:%v", scheme, ip, port)
+	// JoinHostPort adds the brackets required by IPv6 addresses
+	return fmt.Sprintf("%v://%v", scheme, net.JoinHostPort(ip, strconv.Itoa(port)))
 }
 
+// maxConfigSnippet is the number of bytes of the last nginx configuration
+// included in the error returned when a condition is not met
+const maxConfigSnippet = 2048
+
 // WaitForNginxServer waits until the nginx configuration contains a particular server section
 func (f *Framework) WaitForNginxServer(name string, matcher func(cfg string) bool) {
-	err := wait.Poll(Poll, time.Minute*5, f.matchNginxConditions(name, matcher))
-	Expect(err).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s")
+	var last string
+	err := wait.Poll(Poll, time.Minute*5, f.matchNginxConditions(name, matcher, &last))
+	Expect(withConfigSnippet(err, last)).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s")
+}
+
+// WaitForNginxServerNotMatching waits until the matcher does not succeed for a particular server section.
+// The matcher must return false instead of failing the test, i.e. it must not use Expect.
+func (f *Framework) WaitForNginxServerNotMatching(name string, matcher func(cfg string) bool) {
+	var last string
+	notMatcher := func(cfg string) bool {
+		return !matcher(cfg)
+	}
+	err := wait.Poll(Poll, time.Minute*5, f.matchNginxConditions(name, notMatcher, &last))
+	Expect(withConfigSnippet(err, last)).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s to stop matching")
+}
+
+// WaitForNoNginxServer waits until the matcher does not succeed for a particular server section,
+// i.e. after the server is removed from the nginx configuration
+func (f *Framework) WaitForNoNginxServer(name string, matcher func(cfg string) bool) {
+	f.WaitForNginxServerNotMatching(name, matcher)
 }
 
 // WaitForNginxConfiguration waits until the nginx configuration contains a particular configuration
 func (f *Framework) WaitForNginxConfiguration(matcher func(cfg string) bool) {
-	err := wait.Poll(Poll, time.Minute*5, f.matchNginxConditions("", matcher))
-	Expect(err).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s")
+	var last string
+	err := wait.Poll(Poll, time.Minute*5, f.matchNginxConditions("", matcher, &last))
+	Expect(withConfigSnippet(err, last)).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s")
+}
+
+// WaitForNginxCustomConfiguration waits until the nginx configuration between
+// the from and to markers contains a particular configuration
+func (f *Framework) WaitForNginxCustomConfiguration(from string, to string, matcher func(cfg string) bool) {
+	var last string
+	cmd := fmt.Sprintf("cat /etc/nginx/nginx.conf | awk '/%v/,/%v/'", from, to)
+	err := wait.Poll(Poll, time.Minute*5, f.matchNginxCommand(cmd, matcher, &last))
+	Expect(withConfigSnippet(err, last)).NotTo(HaveOccurred(), "unexpected error waiting for nginx server condition/s")
+}
+
+// withConfigSnippet adds the beginning of the last nginx configuration seen to an error
+func withConfigSnippet(err error, cfg string) error {
+	if err == nil {
+		return nil
+	}
+
+	if len(cfg) > maxConfigSnippet {
+		cfg = cfg[:maxConfigSnippet] + "\n[...truncated]"
+	}
+
+	return errors.Wrapf(err, "last nginx configuration seen:\n%v\n", cfg)
 }
 
 func nginxLogs(client kubernetes.Interface, namespace string) (string, error) {
@@ -215,12 +368,169 @@ func nginxLogs(client kubernetes.Interface, namespace string) (string, error) {
 	return "", fmt.Errorf("no nginx ingress controller pod is running (logs)")
 }
 
+// getIngressNGINXPod returns the first running and ready ingress controller pod
+func (f *Framework) getIngressNGINXPod() (*v1.Pod, error) {
+	l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range l.Items {
+		if strings.HasPrefix(p.GetName(), "nginx-ingress-controller") {
+			if isRunning, err := podRunningReady(&p); err == nil && isRunning {
+				return &p, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no nginx ingress controller pod is running")
+}
+
 // NginxLogs returns the logs of the nginx ingress controller pod running
 func (f *Framework) NginxLogs() (string, error) {
 	return nginxLogs(f.KubeClientSet, f.IngressController.Namespace)
 }
 
-func (f *Framework) matchNginxConditions(name string, matcher func(cfg string) bool) wait.ConditionFunc {
+// GetControllerBackends returns the JSON list of backends configured in NGINX.
+// The list is read from the /configuration/backends endpoint of the status
+// port (18080) of NGINX, inside the ingress controller pod.
+func (f *Framework) GetControllerBackends() (string, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return "", err
+	}
+
+	return f.ExecCommand(pod, "curl -s http://localhost:18080/configuration/backends")
+}
+
+// WaitForUpstreamServer waits until the backend named upstream contains an endpoint
+// with the address serverAddr, in ip:port format. Backends are configured dynamically
+// and are not part of nginx.conf, so the list is read using GetControllerBackends.
+func (f *Framework) WaitForUpstreamServer(upstream, serverAddr string, timeout time.Duration) error {
+	err := wait.Poll(Poll, timeout, func() (bool, error) {
+		b, err := f.GetControllerBackends()
+		if err != nil {
+			glog.V(2).Infof("unexpected error reading backends: %v", err)
+			return false, nil
+		}
+
+		var backends []ingress.Backend
+		err = json.Unmarshal([]byte(b), &backends)
+		if err != nil {
+			glog.V(2).Infof("unexpected error decoding backends: %v", err)
+			return false, nil
+		}
+
+		return hasUpstreamServer(backends, upstream, serverAddr), nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "waiting for server %v in upstream %v", serverAddr, upstream)
+	}
+
+	return nil
+}
+
+func hasUpstreamServer(backends []ingress.Backend, upstream, serverAddr string) bool {
+	for _, b := range backends {
+		if b.Name != upstream {
+			continue
+		}
+
+		for _, ep := range b.Endpoints {
+			if net.JoinHostPort(ep.Address, ep.Port) == serverAddr {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WaitForNginxLogEntry waits until the logs of the nginx ingress controller contain substr.
+// Pods are listed again on each attempt to survive restarts of the controller.
+func (f *Framework) WaitForNginxLogEntry(substr string, timeout time.Duration) error {
+	err := wait.Poll(Poll, timeout, func() (bool, error) {
+		logs, err := f.NginxLogs()
+		if err != nil {
+			glog.V(2).Infof("unexpected error reading nginx logs: %v", err)
+			return false, nil
+		}
+
+		return strings.Contains(logs, substr), nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "waiting for %q in nginx logs", substr)
+	}
+
+	return nil
+}
+
+func (f *Framework) matchNginxConditions(name string, matcher func(cfg string) bool, last *string) wait.ConditionFunc {
+	return f.matchNginxCommand(nginxServerCommand(name), matcher, last)
+}
+
+// nginxServerCommand returns the command printing the section of a server in nginx.conf,
+// or the complete configuration if name is empty
+func nginxServerCommand(name string) string {
+	if name == "" {
+		return "cat /etc/nginx/nginx.conf"
+	}
+
+	return fmt.Sprintf("cat /etc/nginx/nginx.conf | awk '/## start server %v/,/## end server %v/'", name, name)
+}
+
+// GetServerLocations returns the locations of a server section of nginx.conf in order
+// of appearance. Prefix locations are returned as the path (e.g. /foo) while other
+// locations keep their modifier (e.g. ~* ^/foo or = /foo). Quotes are removed.
+func (f *Framework) GetServerLocations(server string) ([]string, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := f.ExecCommand(pod, nginxServerCommand(server))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLocations(strings.Join(strings.Fields(cfg), " ")), nil
+}
+
+// parseLocations extracts the arguments of the location directives of a configuration
+func parseLocations(cfg string) []string {
+	fields := strings.Fields(cfg)
+
+	locations := []string{}
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != "location" {
+			continue
+		}
+
+		var args []string
+		for i++; i < len(fields) && fields[i] != "{"; i++ {
+			arg := fields[i]
+			if strings.HasSuffix(arg, "{") {
+				args = append(args, strings.TrimSuffix(arg, "{"))
+				break
+			}
+			args = append(args, arg)
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		locations = append(locations, strings.Replace(strings.Join(args, " "), `"`, "", -1))
+	}
+
+	return locations
+}
+
+// matchNginxCommand runs cmd in the ingress controller pod and passes the output to matcher.
+// The output of the last run is stored in last.
+func (f *Framework) matchNginxCommand(cmd string, matcher func(cfg string) bool, last *string) wait.ConditionFunc {
 	return func() (bool, error) {
 		l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(metav1.ListOptions{
 			LabelSelector: "app.kubernetes.io/name=ingress-nginx",
@@ -233,13 +543,6 @@ func (f *Framework) matchNginxConditions(name string, matcher func(cfg string) b
 			return false, nil
 		}
 
-		var cmd string
-		if name == "" {
-			cmd = fmt.Sprintf("cat /etc/nginx/nginx.conf")
-		} else {
-			cmd = fmt.Sprintf("cat /etc/nginx/nginx.conf | awk '/## start server %v/,/## end server %v/'", name, name)
-		}
-
 		var pod *v1.Pod
 
 		for _, p := range l.Items {
@@ -260,6 +563,10 @@ func (f *Framework) matchNginxConditions(name string, matcher func(cfg string) b
 			return false, err
 		}
 
+		if last != nil {
+			*last = o
+		}
+
 		var match bool
 		errs := InterceptGomegaFailures(func() {
 			if glog.V(10) && len(o) > 0 {
@@ -284,6 +591,52 @@ func (f *Framework) matchNginxConditions(name string, matcher func(cfg string) b
 	}
 }
 
+// GetNginxUpstreams returns the names of the upstream blocks defined in nginx.conf.
+// Names are returned once, in order of appearance.
+func (f *Framework) GetNginxUpstreams() ([]string, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := f.ExecCommand(pod, "cat /etc/nginx/nginx.conf")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNginxUpstreams(strings.Join(strings.Fields(cfg), " ")), nil
+}
+
+// parseNginxUpstreams extracts the names of the upstream directives of a configuration
+func parseNginxUpstreams(cfg string) []string {
+	fields := strings.Fields(cfg)
+
+	upstreams := []string{}
+	seen := map[string]bool{}
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] != "upstream" {
+			continue
+		}
+
+		name := fields[i+1]
+		if strings.HasSuffix(name, "{") {
+			name = strings.TrimSuffix(name, "{")
+		} else if i+2 >= len(fields) || !strings.HasPrefix(fields[i+2], "{") {
+			// not a block, e.g. part of a comment
+			continue
+		}
+
+		if name == "" || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		upstreams = append(upstreams, name)
+	}
+
+	return upstreams
+}
+
 func (f *Framework) getNginxConfigMap() (*v1.ConfigMap, error) {
 	if f.KubeClientSet == nil {
 		return nil, fmt.Errorf("KubeClientSet not initialized")
@@ -342,6 +695,77 @@ func (f *Framework) UpdateNginxConfigMapData(key string, value string) {
 	f.SetNginxConfigMapData(config)
 }
 
+// WithNginxConfigMap applies overrides to the data of ingress-nginx's nginx-configuration
+// map, runs body and restores the original data, even if body panics
+func (f *Framework) WithNginxConfigMap(overrides map[string]string, body func()) {
+	original, err := f.GetNginxConfigMapData()
+	Expect(err).NotTo(HaveOccurred(), "unexpected error reading configmap")
+
+	snapshot := make(map[string]string, len(original))
+	data := make(map[string]string, len(original)+len(overrides))
+	for k, v := range original {
+		snapshot[k] = v
+		data[k] = v
+	}
+	for k, v := range overrides {
+		data[k] = v
+	}
+
+	defer f.SetNginxConfigMapData(snapshot)
+
+	f.SetNginxConfigMapData(data)
+	body()
+}
+
+// ScaleControllerTo changes the number of replicas of the ingress controller
+// and waits until all of them are ready
+func (f *Framework) ScaleControllerTo(replicas int) error {
+	return UpdateDeployment(f.KubeClientSet, f.IngressController.Namespace, "nginx-ingress-controller", replicas, nil)
+}
+
+// RestartController deletes the ingress controller pods and waits until the replacements are ready
+func (f *Framework) RestartController() error {
+	opts := metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+	}
+
+	l, err := f.KubeClientSet.CoreV1().Pods(f.IngressController.Namespace).List(opts)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range l.Items {
+		err := f.KubeClientSet.CoreV1().Pods(pod.Namespace).Delete(pod.Name, metav1.NewDeleteOptions(0))
+		if err != nil {
+			return errors.Wrapf(err, "deleting pod %v", pod.Name)
+		}
+	}
+
+	// the deleted pods can be running until they are removed
+	err = wait.Poll(Poll, time.Minute*5, func() (bool, error) {
+		for _, pod := range l.Items {
+			_, err := f.KubeClientSet.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if err == nil {
+				return false, nil
+			}
+			if !k8sErrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "waiting for the ingress controller pods to be deleted")
+	}
+
+	err = WaitForPodsReady(f.KubeClientSet, 5*time.Minute, len(l.Items), f.IngressController.Namespace, opts)
+	if err != nil {
+		return errors.Wrap(err, "waiting for the ingress controller pods to be ready")
+	}
+
+	return nil
+}
+
 // UpdateDeployment runs the given updateFunc on the deployment and waits for it to be updated
 func UpdateDeployment(kubeClientSet kubernetes.Interface, namespace string, name string, replicas int, updateFunc func(d *appsv1beta1.Deployment) error) error {
 	deployment, err := kubeClientSet.AppsV1beta1().Deployments(namespace).Get(name, metav1.GetOptions{})
@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestGetNginxURL(t *testing.T) {
+	RegisterTestingT(t)
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ingress-nginx",
+			Namespace: "ingress",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{
+				{Name: "http", NodePort: 30080},
+				{Name: "https", NodePort: 30443},
+			},
+		},
+	}
+
+	f := &Framework{
+		KubeClientSet:     fake.NewSimpleClientset(svc),
+		IngressController: &ingressController{Namespace: "ingress"},
+	}
+
+	defer os.Setenv("NODE_IP", os.Getenv("NODE_IP"))
+
+	tests := []struct {
+		title    string
+		nodeIP   string
+		scheme   RequestScheme
+		expected string
+	}{
+		{"IPv4 address", "10.0.0.1", HTTP, "http://10.0.0.1:30080"},
+		{"IPv6 address", "fd00::1", HTTP, "http://[fd00::1]:30080"},
+		{"IPv6 address using HTTPS", "2001:db8::10", HTTPS, "https://[2001:db8::10]:30443"},
+	}
+
+	for _, test := range tests {
+		os.Setenv("NODE_IP", test.nodeIP)
+
+		url := f.GetNginxURL(test.scheme)
+		if url != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, url)
+		}
+	}
+}
+
+func TestParseNginxUpstreams(t *testing.T) {
+	tests := []struct {
+		title    string
+		cfg      string
+		expected []string
+	}{
+		{"no upstreams", "http { server { listen 80; } }", []string{}},
+		{"single upstream", "upstream upstream_balancer { server 0.0.0.1; }", []string{"upstream_balancer"}},
+		{"upstream without space", "upstream upstream_balancer{ server 0.0.0.1; }", []string{"upstream_balancer"}},
+		{"multiple whitespace", "upstream\n\t  default-http-svc-80   {\n server 10.0.0.1:8080;\n}", []string{"default-http-svc-80"}},
+		{"duplicated upstream", "http { upstream a { } } stream { upstream a { } upstream b { } }", []string{"a", "b"}},
+		{"comment", "# try the next upstream server before returning an error", []string{}},
+	}
+
+	for _, test := range tests {
+		upstreams := parseNginxUpstreams(test.cfg)
+		if !reflect.DeepEqual(upstreams, test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, upstreams)
+		}
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	tests := []struct {
+		title    string
+		cfg      string
+		expected []string
+	}{
+		{"no locations", "server { listen 80; }", []string{}},
+		{"prefix locations", "location /foo { } location / { }", []string{"/foo", "/"}},
+		{"regex location", `location ~* "^/foo" { } location ~* ^/ { }`, []string{"~* ^/foo", "~* ^/"}},
+		{"exact and named locations", "location = /_auth { } location @custom_404 { }", []string{"= /_auth", "@custom_404"}},
+		{"location without space", "location /bar{ }", []string{"/bar"}},
+	}
+
+	for _, test := range tests {
+		locations := parseLocations(test.cfg)
+		if !reflect.DeepEqual(locations, test.expected) {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, locations)
+		}
+	}
+}
+
+func TestHasUpstreamServer(t *testing.T) {
+	backends := []ingress.Backend{
+		{
+			Name: "default-http-svc-80",
+			Endpoints: []ingress.Endpoint{
+				{Address: "10.0.0.1", Port: "8080"},
+				{Address: "fd00::1", Port: "8080"},
+			},
+		},
+		{Name: "upstream-default-backend"},
+	}
+
+	tests := []struct {
+		title      string
+		upstream   string
+		serverAddr string
+		expected   bool
+	}{
+		{"existing server", "default-http-svc-80", "10.0.0.1:8080", true},
+		{"IPv6 server", "default-http-svc-80", "[fd00::1]:8080", true},
+		{"unknown server", "default-http-svc-80", "10.0.0.2:8080", false},
+		{"server of another upstream", "upstream-default-backend", "10.0.0.1:8080", false},
+		{"unknown upstream", "default-foo-80", "10.0.0.1:8080", false},
+	}
+
+	for _, test := range tests {
+		found := hasUpstreamServer(backends, test.upstream, test.serverAddr)
+		if found != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, found)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// GRPCDial connects to the HTTPS port of NGINX using gRPC. The authority is
+// used as the :authority pseudo-header and as the TLS server name.
+// The connection attempt is limited by TestContext.GRPCDialTimeout.
+func (f *Framework) GRPCDial(authority string) (*grpc.ClientConn, error) {
+	u, err := url.Parse(f.IngressController.HTTPSURL)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := credentials.NewTLS(&tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         authority,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), TestContext.GRPCDialTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, u.Host,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithAuthority(authority),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error dialing %v (authority %v): %v", u.Host, authority, err)
+	}
+
+	return conn, nil
+}
+
+// GRPCUnaryCall invokes a unary gRPC method, i.e. /package.Service/Method,
+// sending the protobuf encoded request and returning the encoded response.
+// Encoded messages are used to avoid depending on generated code.
+func GRPCUnaryCall(conn *grpc.ClientConn, method string, request []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), TestContext.GRPCDialTimeout)
+	defer cancel()
+
+	var reply []byte
+	err := conn.Invoke(ctx, method, request, &reply, grpc.CallCustomCodec(rawCodec{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// rawCodec passes already encoded messages through gRPC
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("expected []byte but %T was provided", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("expected *[]byte but %T was provided", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "raw"
+}
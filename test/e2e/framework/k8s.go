@@ -66,7 +66,12 @@ func (f *Framework) EnsureConfigMap(configMap *api.ConfigMap) (*api.ConfigMap, e
 }
 
 // EnsureIngress creates an Ingress object or returns it if it already exists.
+// Ingresses without a namespace are created in the namespace of the framework.
 func (f *Framework) EnsureIngress(ingress *extensions.Ingress) *extensions.Ingress {
+	if ingress.Namespace == "" {
+		ingress.Namespace = f.Namespace
+	}
+
 	ing, err := f.KubeClientSet.ExtensionsV1beta1().Ingresses(ingress.Namespace).Update(ingress)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
@@ -87,6 +92,55 @@ func (f *Framework) EnsureIngress(ingress *extensions.Ingress) *extensions.Ingre
 	return ing
 }
 
+// GetIngress returns the current state of an Ingress of the framework namespace
+func (f *Framework) GetIngress(name string) (*extensions.Ingress, error) {
+	return f.KubeClientSet.ExtensionsV1beta1().Ingresses(f.Namespace).Get(name, metav1.GetOptions{})
+}
+
+// UpdateIngress runs the given updateFunc on an Ingress of the framework namespace and updates it
+func (f *Framework) UpdateIngress(name string, updateFunc func(ing *extensions.Ingress) error) (*extensions.Ingress, error) {
+	ing, err := f.GetIngress(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ing.Annotations == nil {
+		ing.Annotations = make(map[string]string)
+	}
+
+	if updateFunc != nil {
+		if err := updateFunc(ing); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.KubeClientSet.ExtensionsV1beta1().Ingresses(f.Namespace).Update(ing)
+}
+
+// UpdateIngressAnnotation sets the given annotations in an Ingress of the framework namespace
+// and waits until the server of its first rule satisfies the matcher
+func (f *Framework) UpdateIngressAnnotation(name string, annotations map[string]string, matcher func(cfg string) bool) {
+	ing, err := f.UpdateIngress(name, func(ing *extensions.Ingress) error {
+		for k, v := range annotations {
+			ing.Annotations[k] = v
+		}
+		return nil
+	})
+	Expect(err).NotTo(HaveOccurred(), "unexpected error updating ingress annotations")
+
+	server := "_"
+	if len(ing.Spec.Rules) > 0 && ing.Spec.Rules[0].Host != "" {
+		server = ing.Spec.Rules[0].Host
+	}
+
+	f.WaitForNginxServer(server, matcher)
+}
+
+// DeleteIngress deletes an Ingress of the framework namespace
+func (f *Framework) DeleteIngress(name string) error {
+	return f.KubeClientSet.ExtensionsV1beta1().Ingresses(f.Namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
 // EnsureService creates a Service object or returns it if it already exists.
 func (f *Framework) EnsureService(service *core.Service) *core.Service {
 	s, err := f.KubeClientSet.CoreV1().Services(service.Namespace).Update(service)
@@ -118,29 +172,136 @@ func (f *Framework) EnsureDeployment(deployment *extensions.Deployment) (*extens
 	return d, nil
 }
 
+// WaitForIngressAddress waits until the status of an Ingress contains an IP address or hostname and returns it.
+func (f *Framework) WaitForIngressAddress(ns, name string, timeout time.Duration) (string, error) {
+	var address string
+	err := wait.Poll(Poll, timeout, func() (bool, error) {
+		ing, err := f.KubeClientSet.ExtensionsV1beta1().Ingresses(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			if k8sErrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				address = lb.IP
+				return true, nil
+			}
+			if lb.Hostname != "" {
+				address = lb.Hostname
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("waiting for an address in the status of ingress %v/%v: %v", ns, name, err)
+	}
+
+	return address, nil
+}
+
+// WaitForEndpoints waits until the Endpoints of a service of the framework namespace
+// contain at least count ready addresses
+func (f *Framework) WaitForEndpoints(name string, count int, timeout time.Duration) error {
+	return WaitForEndpointsInNamespace(f.KubeClientSet, f.Namespace, name, count, timeout)
+}
+
+// WaitForEndpointsInNamespace waits until the Endpoints of a service contain at least count ready addresses
+func WaitForEndpointsInNamespace(kubeClientSet kubernetes.Interface, namespace, name string, count int, timeout time.Duration) error {
+	err := wait.Poll(Poll, timeout, func() (bool, error) {
+		endpoints, err := kubeClientSet.CoreV1().Endpoints(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			if k8sErrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		r := 0
+		for _, subset := range endpoints.Subsets {
+			r += len(subset.Addresses)
+		}
+
+		return r >= count, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %v endpoints of service %v/%v: %v", count, namespace, name, err)
+	}
+
+	return nil
+}
+
 // WaitForPodsReady waits for a given amount of time until a group of Pods is running in the given namespace.
 func WaitForPodsReady(kubeClientSet kubernetes.Interface, timeout time.Duration, expectedReplicas int, namespace string, opts metav1.ListOptions) error {
+	// WaitForPodsCondition requires at least one pod
+	if expectedReplicas == 0 {
+		return wait.Poll(2*time.Second, timeout, func() (bool, error) {
+			pl, err := kubeClientSet.CoreV1().Pods(namespace).List(opts)
+			if err != nil {
+				return false, err
+			}
+
+			return len(pl.Items) == 0, nil
+		})
+	}
+
+	// pods being replaced can be ready, so the condition is checked
+	// again until the number of pods is the expected one
+	deadline := time.Now().Add(timeout)
+	for {
+		err := WaitForPodsCondition(kubeClientSet, time.Until(deadline), namespace, opts, podReady)
+		if err != nil {
+			return err
+		}
+
+		pl, err := kubeClientSet.CoreV1().Pods(namespace).List(opts)
+		if err != nil {
+			return err
+		}
+
+		if len(pl.Items) == expectedReplicas {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("expected %v ready pods but %v were found", expectedReplicas, len(pl.Items))
+		}
+	}
+}
+
+// WaitForPodsCondition waits for a given amount of time until there is at least one Pod
+// in the given namespace and all of them satisfy the condition.
+func WaitForPodsCondition(kubeClientSet kubernetes.Interface, timeout time.Duration, namespace string, opts metav1.ListOptions, cond func(*core.Pod) bool) error {
 	return wait.Poll(2*time.Second, timeout, func() (bool, error) {
 		pl, err := kubeClientSet.CoreV1().Pods(namespace).List(opts)
 		if err != nil {
 			return false, err
 		}
 
-		r := 0
-		for _, p := range pl.Items {
-			if isRunning, _ := podRunningReady(&p); isRunning {
-				r++
-			}
+		if len(pl.Items) == 0 {
+			return false, nil
 		}
 
-		if r == expectedReplicas {
-			return true, nil
+		for i := range pl.Items {
+			if !cond(&pl.Items[i]) {
+				return false, nil
+			}
 		}
 
-		return false, nil
+		return true, nil
 	})
 }
 
+// podReady returns true if the pod is running and ready
+func podReady(p *core.Pod) bool {
+	ready, _ := podRunningReady(p)
+	return ready
+}
+
 // podRunningReady checks whether pod p's phase is running and it has a ready
 // condition of status true.
 func podRunningReady(p *core.Pod) (bool, error) {
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestPod(name string, phase v1.PodPhase, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test",
+			Labels:    map[string]string{"app": "test"},
+		},
+		Status: v1.PodStatus{
+			Phase: phase,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodReady, Status: status},
+			},
+		},
+	}
+}
+
+func TestWaitForPodsCondition(t *testing.T) {
+	succeeded := func(p *v1.Pod) bool {
+		return p.Status.Phase == v1.PodSucceeded
+	}
+
+	tests := []struct {
+		title  string
+		pods   []*v1.Pod
+		cond   func(*v1.Pod) bool
+		expErr bool
+	}{
+		{"all pods completed", []*v1.Pod{newTestPod("a", v1.PodSucceeded, false), newTestPod("b", v1.PodSucceeded, false)}, succeeded, false},
+		{"one pod running", []*v1.Pod{newTestPod("a", v1.PodSucceeded, false), newTestPod("b", v1.PodRunning, true)}, succeeded, true},
+		{"no pods", []*v1.Pod{}, succeeded, true},
+		{"custom condition on the name", []*v1.Pod{newTestPod("a", v1.PodPending, false)}, func(p *v1.Pod) bool { return p.Name == "a" }, false},
+	}
+
+	for _, test := range tests {
+		client := fake.NewSimpleClientset()
+		for _, p := range test.pods {
+			client.CoreV1().Pods("test").Create(p)
+		}
+
+		err := WaitForPodsCondition(client, 3*time.Second, "test", metav1.ListOptions{LabelSelector: "app=test"}, test.cond)
+		if test.expErr && err == nil {
+			t.Errorf("%v: expected an error but none was returned", test.title)
+		}
+		if !test.expErr && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.title, err)
+		}
+	}
+}
+
+func TestWaitForPodsReady(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newTestPod("a", v1.PodRunning, true),
+		newTestPod("b", v1.PodRunning, true),
+	)
+
+	err := WaitForPodsReady(client, 3*time.Second, 2, "test", metav1.ListOptions{LabelSelector: "app=test"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = WaitForPodsReady(client, 3*time.Second, 3, "test", metav1.ListOptions{LabelSelector: "app=test"})
+	if err == nil {
+		t.Errorf("expected an error but none was returned")
+	}
+
+	err = WaitForPodsReady(client, 3*time.Second, 0, "other", metav1.ListOptions{LabelSelector: "app=test"})
+	if err != nil {
+		t.Errorf("unexpected error waiting for no pods: %v", err)
+	}
+
+	err = WaitForPodsReady(client, 3*time.Second, 0, "test", metav1.ListOptions{LabelSelector: "app=test"})
+	if err == nil {
+		t.Errorf("expected an error but none was returned")
+	}
+
+	client.CoreV1().Pods("test").Create(newTestPod("c", v1.PodRunning, false))
+	err = WaitForPodsReady(client, 3*time.Second, 3, "test", metav1.ListOptions{LabelSelector: "app=test"})
+	if err == nil {
+		t.Errorf("expected an error but none was returned")
+	}
+}
+
+func TestGetIngress(t *testing.T) {
+	f := &Framework{
+		Namespace:     "test",
+		KubeClientSet: fake.NewSimpleClientset(),
+	}
+
+	annotations := map[string]string{
+		"nginx.ingress.kubernetes.io/abpolicy":      "true",
+		"nginx.ingress.kubernetes.io/abpolicy-host": "foo.bar.com",
+	}
+	ing := NewSingleIngress("foo.bar.com", "/", "foo.bar.com", f.Namespace, "http-svc", 80, &annotations)
+
+	_, err := f.KubeClientSet.ExtensionsV1beta1().Ingresses(f.Namespace).Create(ing)
+	if err != nil {
+		t.Fatalf("unexpected error creating ingress: %v", err)
+	}
+
+	current, err := f.GetIngress("foo.bar.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(current.Annotations, annotations) {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", annotations, current.Annotations)
+	}
+
+	_, err = f.GetIngress("bar.foo.com")
+	if err == nil {
+		t.Errorf("expected an error for a missing ingress but none was returned")
+	}
+}
@@ -18,8 +18,11 @@ package framework
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os/exec"
+	"time"
 
 	"k8s.io/api/core/v1"
 )
@@ -50,3 +53,57 @@ func Logs(pod *v1.Pod) (string, error) {
 
 	return execOut.String(), nil
 }
+
+// StreamNginxLogs follows the logs of the ingress controller pod, writing them to out,
+// until the context is cancelled. If the pod restarts or is replaced the logs of the
+// new container are followed. Only an error writing to out stops the streaming.
+func (f *Framework) StreamNginxLogs(ctx context.Context, out io.Writer) error {
+	var (
+		lastPod string
+		since   time.Time
+	)
+
+	for {
+		pod, err := f.getIngressNGINXPod()
+		if err == nil {
+			args := []string{"logs", "--follow", "--namespace", pod.Namespace, pod.Name}
+			if pod.Name == lastPod {
+				// the container restarted, skip the entries already written
+				args = append(args, fmt.Sprintf("--since-time=%v", since.Format(time.RFC3339)))
+			}
+
+			w := &errWriter{w: out}
+			cmd := exec.CommandContext(ctx, KubectlPath, args...)
+			cmd.Stdout = w
+			cmd.Run()
+
+			// the entries are written until kubectl exits
+			lastPod = pod.Name
+			since = time.Now()
+
+			if w.err != nil {
+				return w.err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(Poll):
+		}
+	}
+}
+
+// errWriter keeps the first error returned by the underlying writer
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	n, err := e.w.Write(p)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
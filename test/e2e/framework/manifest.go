@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// manifestObject is an object created by ApplyManifest
+type manifestObject struct {
+	client dynamic.ResourceInterface
+	name   string
+}
+
+// ApplyManifest creates the objects of a YAML manifest, with one or many documents,
+// in the framework namespace. Objects that already exist are updated. The objects
+// created by the manifest are deleted after the test.
+func (f *Framework) ApplyManifest(yaml string) error {
+	groupResources, err := restmapper.GetAPIGroupResources(f.KubeClientSet.Discovery())
+	if err != nil {
+		return fmt.Errorf("unexpected error reading API resources: %v", err)
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(yaml), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unexpected error decoding manifest: %v", err)
+		}
+
+		// empty documents
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return fmt.Errorf("unexpected error mapping %v %v: %v", gvk, obj.GetName(), err)
+		}
+
+		var client dynamic.ResourceInterface
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj.SetNamespace(f.Namespace)
+			client = f.DynamicClient.Resource(mapping.Resource).Namespace(f.Namespace)
+		} else {
+			client = f.DynamicClient.Resource(mapping.Resource)
+		}
+
+		created, err := createOrUpdate(client, obj)
+		if err != nil {
+			return fmt.Errorf("unexpected error applying %v %v: %v", gvk.Kind, obj.GetName(), err)
+		}
+
+		if created {
+			f.manifestObjects = append(f.manifestObjects, manifestObject{client, obj.GetName()})
+		}
+	}
+}
+
+// createOrUpdate creates obj or, if it already exists, updates it.
+// It returns true when the object was created.
+func createOrUpdate(client dynamic.ResourceInterface, obj *unstructured.Unstructured) (bool, error) {
+	_, err := client.Create(obj, metav1.CreateOptions{})
+	if !k8sErrors.IsAlreadyExists(err) {
+		return err == nil, err
+	}
+
+	current, err := client.Get(obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	obj.SetResourceVersion(current.GetResourceVersion())
+	_, err = client.Update(obj, metav1.UpdateOptions{})
+	return false, err
+}
+
+// deleteManifestObjects deletes the objects created by ApplyManifest
+func (f *Framework) deleteManifestObjects() error {
+	for _, obj := range f.manifestObjects {
+		err := obj.client.Delete(obj.name, &metav1.DeleteOptions{})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("unexpected error deleting manifest object %v: %v", obj.name, err)
+		}
+	}
+
+	f.manifestObjects = nil
+	return nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// metricsURL is the address of the prometheus endpoint inside the ingress controller pod
+	metricsURL = "http://localhost:10254/metrics"
+
+	// reloadMetric is the counter of successful reloads of the ingress controller
+	reloadMetric = "nginx_ingress_controller_success"
+)
+
+// ErrMetricsUnavailable is returned when the resource metrics API is not served
+// by the cluster (metrics-server is not installed) or has no data for the pod yet.
+// Tests should skip instead of failing when it is returned.
+var ErrMetricsUnavailable = errors.New("resource metrics API is not available")
+
+// podMetrics is the subset of a metrics.k8s.io PodMetrics object used by the framework
+type podMetrics struct {
+	Containers []struct {
+		Name  string                       `json:"name"`
+		Usage map[string]resource.Quantity `json:"usage"`
+	} `json:"containers"`
+}
+
+// GetMetrics returns the prometheus metrics exposed by the ingress controller
+// using the text exposition format
+func (f *Framework) GetMetrics() (string, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return "", err
+	}
+
+	return f.ExecCommand(pod, fmt.Sprintf("curl -s %v", metricsURL))
+}
+
+// GetMetricValue returns the value of the sample of a metric containing the given labels.
+// Only counter, gauge and untyped metrics are supported.
+func (f *Framework) GetMetricValue(name string, labels map[string]string) (float64, error) {
+	metrics, err := f.GetMetrics()
+	if err != nil {
+		return 0, err
+	}
+
+	return parseMetricValue(metrics, name, labels)
+}
+
+func parseMetricValue(metrics, name string, labels map[string]string) (float64, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(metrics))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected error parsing metrics: %v", err)
+	}
+
+	family, ok := families[name]
+	if !ok {
+		return 0, fmt.Errorf("metric %v not found", name)
+	}
+
+	for _, m := range family.GetMetric() {
+		if !hasLabels(m, labels) {
+			continue
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			return m.GetCounter().GetValue(), nil
+		case dto.MetricType_GAUGE:
+			return m.GetGauge().GetValue(), nil
+		case dto.MetricType_UNTYPED:
+			return m.GetUntyped().GetValue(), nil
+		default:
+			return 0, fmt.Errorf("metric %v has unsupported type %v", name, family.GetType())
+		}
+	}
+
+	return 0, fmt.Errorf("metric %v with labels %v not found", name, labels)
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	found := 0
+	for _, l := range m.GetLabel() {
+		if v, ok := labels[l.GetName()]; ok && v == l.GetValue() {
+			found++
+		}
+	}
+
+	return found == len(labels)
+}
+
+// GetNginxReloadCount returns the number of successful reloads of the ingress controller,
+// using the nginx_ingress_controller_success counter
+func (f *Framework) GetNginxReloadCount() (int, error) {
+	v, err := f.GetMetricValue(reloadMetric, map[string]string{})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(v), nil
+}
+
+// ExpectNoReloadDuring runs action and fails the test if the ingress controller
+// reloads NGINX before d elapses
+func (f *Framework) ExpectNoReloadDuring(d time.Duration, action func()) {
+	before, err := f.GetNginxReloadCount()
+	Expect(err).NotTo(HaveOccurred(), "unexpected error reading the reload count")
+
+	action()
+	time.Sleep(d)
+
+	after, err := f.GetNginxReloadCount()
+	Expect(err).NotTo(HaveOccurred(), "unexpected error reading the reload count")
+	Expect(after).Should(Equal(before), "unexpected NGINX reload")
+}
+
+// WaitForReloadsToSettle waits until the number of reloads of the ingress
+// controller has not changed for the quiet duration
+func (f *Framework) WaitForReloadsToSettle(quiet time.Duration, timeout time.Duration) error {
+	last, err := f.GetNginxReloadCount()
+	if err != nil {
+		return err
+	}
+	changed := time.Now()
+
+	err = wait.Poll(Poll, timeout, func() (bool, error) {
+		count, err := f.GetNginxReloadCount()
+		if err != nil {
+			return false, err
+		}
+
+		if count != last {
+			last = count
+			changed = time.Now()
+			return false, nil
+		}
+
+		return time.Since(changed) >= quiet, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "waiting for NGINX reloads to settle (last count %v)", last)
+	}
+
+	return nil
+}
+
+// GetControllerMemory returns the memory used by the ingress controller pod, in bytes,
+// as reported by the resource metrics API. ErrMetricsUnavailable is returned if
+// metrics-server is not running in the cluster.
+func (f *Framework) GetControllerMemory() (int64, error) {
+	pod, err := f.getIngressNGINXPod()
+	if err != nil {
+		return 0, err
+	}
+
+	path := fmt.Sprintf("/apis/metrics.k8s.io/v1beta1/namespaces/%v/pods/%v", pod.Namespace, pod.Name)
+	b, err := f.KubeClientSet.CoreV1().RESTClient().Get().AbsPath(path).DoRaw()
+	if err != nil {
+		if k8sErrors.IsNotFound(err) || k8sErrors.IsServiceUnavailable(err) {
+			return 0, ErrMetricsUnavailable
+		}
+		return 0, errors.Wrapf(err, "reading metrics of pod %v", pod.Name)
+	}
+
+	return parsePodMemory(b)
+}
+
+func parsePodMemory(b []byte) (int64, error) {
+	var m podMetrics
+	err := json.Unmarshal(b, &m)
+	if err != nil {
+		return 0, errors.Wrap(err, "decoding pod metrics")
+	}
+
+	if len(m.Containers) == 0 {
+		return 0, ErrMetricsUnavailable
+	}
+
+	var total int64
+	for _, c := range m.Containers {
+		mem, ok := c.Usage["memory"]
+		if !ok {
+			continue
+		}
+		total += mem.Value()
+	}
+
+	return total, nil
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import "testing"
+
+func TestParsePodMemory(t *testing.T) {
+	tests := []struct {
+		title    string
+		metrics  string
+		expected int64
+		err      error
+	}{
+		{"single container", `{"containers":[{"name":"nginx-ingress-controller","usage":{"cpu":"10m","memory":"100Mi"}}]}`, 100 * 1024 * 1024, nil},
+		{"multiple containers", `{"containers":[{"name":"a","usage":{"memory":"1Ki"}},{"name":"b","usage":{"memory":"1Ki"}}]}`, 2048, nil},
+		{"no containers", `{"containers":[]}`, 0, ErrMetricsUnavailable},
+	}
+
+	for _, test := range tests {
+		memory, err := parsePodMemory([]byte(test.metrics))
+		if err != test.err {
+			t.Errorf("%v: expected error \"%v\", but \"%v\" was returned", test.title, test.err, err)
+		}
+		if memory != test.expected {
+			t.Errorf("%v: expected \"%v\", but \"%v\" was returned", test.title, test.expected, memory)
+		}
+	}
+}
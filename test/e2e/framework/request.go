@@ -0,0 +1,290 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/http2"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Request sends a GET request to NGINX using the Host header to select the server.
+// Certificates are not verified for HTTPS requests. Requests failing before
+// a response is received are retried until defaultTimeout expires.
+func (f *Framework) Request(scheme RequestScheme, host, path string, headers map[string]string) (int, http.Header, string, error) {
+	url := f.nginxURL(scheme)
+	client := f.newHTTPClient(host)
+
+	var (
+		status  int
+		header  http.Header
+		body    string
+		lastErr error
+	)
+
+	err := wait.PollImmediate(Poll, defaultTimeout, func() (bool, error) {
+		status, header, body, lastErr = doRequest(client, url, host, path, headers)
+		if lastErr != nil {
+			Logf("unexpected error sending request to %v%v (retrying): %v", host, path, lastErr)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			err = lastErr
+		}
+		return 0, nil, "", fmt.Errorf("unexpected error sending request to %v%v: %v", host, path, err)
+	}
+
+	return status, header, body, nil
+}
+
+// ExpectResponseHeader sends a request to NGINX and fails the test if the
+// value of the header in the response is not equal to value
+func (f *Framework) ExpectResponseHeader(scheme RequestScheme, host, path, header, value string) {
+	_, headers, _, err := f.Request(scheme, host, path, nil)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(headers.Get(header)).Should(Equal(value), "unexpected value of header %v in the response of %v%v", header, host, path)
+}
+
+// ExpectResponseHeaderContains sends a request to NGINX and fails the test if the
+// value of the header in the response does not contain value
+func (f *Framework) ExpectResponseHeaderContains(scheme RequestScheme, host, path, header, value string) {
+	_, headers, _, err := f.Request(scheme, host, path, nil)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(headers.Get(header)).Should(ContainSubstring(value), "unexpected value of header %v in the response of %v%v", header, host, path)
+}
+
+// ExpectBackendReached sends up to attempts requests to NGINX and fails the test
+// if none of the responses contains expectedBody
+func (f *Framework) ExpectBackendReached(scheme RequestScheme, host, path string, headers map[string]string, expectedBody string, attempts int) {
+	for i := 0; i < attempts; i++ {
+		_, _, body, err := f.Request(scheme, host, path, headers)
+		Expect(err).NotTo(HaveOccurred())
+
+		if strings.Contains(body, expectedBody) {
+			return
+		}
+	}
+
+	Failf("no response of %v%v contained %q after %v attempts", host, path, expectedBody, attempts)
+}
+
+// LoadTestResult contains the responses received by LoadTest
+type LoadTestResult struct {
+	// Statuses contains the number of responses received for each status code
+	Statuses map[int]int
+	// Backends contains the number of responses received from each backend,
+	// identified by the Hostname line of the echo server
+	Backends map[string]int
+}
+
+// LoadTest sends total GET requests to NGINX using concurrency workers and
+// returns the responses received. Requests are not retried; the first error
+// stops the test.
+func (f *Framework) LoadTest(scheme RequestScheme, host, path string, concurrency, total int) (LoadTestResult, error) {
+	result := LoadTestResult{
+		Statuses: map[int]int{},
+		Backends: map[string]int{},
+	}
+
+	if concurrency < 1 {
+		return result, fmt.Errorf("concurrency must be greater than zero")
+	}
+
+	url := f.nginxURL(scheme)
+	client := f.newHTTPClient(host)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	requests := make(chan struct{}, total)
+	for i := 0; i < total; i++ {
+		requests <- struct{}{}
+	}
+	close(requests)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				status, _, body, err := doRequest(client, url, host, path, nil)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					result.Statuses[status]++
+					if backend := echoHostname(body); backend != "" {
+						result.Backends[backend]++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return result, fmt.Errorf("unexpected error sending requests to %v%v: %v", host, path, firstErr)
+	}
+
+	return result, nil
+}
+
+// echoHostname returns the value of the Hostname line of
+// a response of the echo server, or an empty string
+func echoHostname(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Hostname:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Hostname:"))
+		}
+	}
+
+	return ""
+}
+
+// nginxURL returns the base URL of NGINX for a scheme
+func (f *Framework) nginxURL(scheme RequestScheme) string {
+	url := f.IngressController.HTTPURL
+	if scheme == HTTPS {
+		url = f.IngressController.HTTPSURL
+	}
+	if url == "" {
+		url = f.GetNginxURL(scheme)
+	}
+
+	return url
+}
+
+// requestTimeout returns the timeout of a single HTTP request
+func (f *Framework) requestTimeout() time.Duration {
+	if f.RequestTimeout == 0 {
+		return defaultRequestTimeout
+	}
+
+	return f.RequestTimeout
+}
+
+// newHTTPClient returns a client that does not verify certificates
+// nor follow redirects, using host as the TLS server name
+func (f *Framework) newHTTPClient(host string) *http.Client {
+	return &http.Client{
+		Timeout: f.requestTimeout(),
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				ServerName:         host,
+			},
+		},
+		// redirects are returned to the caller
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// doRequest sends a single GET request and reads the complete response
+func doRequest(client *http.Client, url, host, path string, headers map[string]string) (int, http.Header, string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%v%v", url, path), nil)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	req.Host = host
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, "", err
+	}
+
+	return resp.StatusCode, resp.Header, string(body), nil
+}
+
+// HTTP2Request sends a request to the HTTPS port of NGINX using HTTP/2.
+// The Host header, if present in headers, is also used as the TLS server name.
+// An error is returned if the connection did not negotiate the h2 protocol.
+func (f *Framework) HTTP2Request(method, path string, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%v%v", f.IngressController.HTTPSURL, path), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Timeout: f.requestTimeout(),
+		Transport: &http2.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				ServerName:         req.Host,
+				NextProtos:         []string{http2.NextProtoTLS},
+			},
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unexpected error sending HTTP/2 request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 || resp.TLS == nil || resp.TLS.NegotiatedProtocol != http2.NextProtoTLS {
+		return resp, nil, fmt.Errorf("expected protocol %v but %v was negotiated", http2.NextProtoTLS, resp.Proto)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("unexpected error reading HTTP/2 response body: %v", err)
+	}
+
+	return resp, body, nil
+}
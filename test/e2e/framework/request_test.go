@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	// accepts connections but never sends a response
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error creating listener: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// keep the connection open until the listener is closed
+			defer conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		title string
+		url   string
+	}{
+		{"unresponsive upstream", "http://" + l.Addr().String()},
+		{"non-routable host", "http://10.255.255.1"},
+	}
+
+	for _, test := range tests {
+		f := &Framework{
+			RequestTimeout:    500 * time.Millisecond,
+			IngressController: &ingressController{HTTPURL: test.url},
+		}
+
+		start := time.Now()
+		_, err := f.LoadTest(HTTP, "foo.bar", "/", 1, 1)
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Errorf("%v: expected an error but none was returned", test.title)
+			continue
+		}
+
+		if elapsed > 5*time.Second {
+			t.Errorf("%v: expected the request to fail within 5s, but it took %v", test.title, elapsed)
+		}
+	}
+}
+
+func TestDefaultRequestTimeout(t *testing.T) {
+	f := &Framework{}
+	if f.requestTimeout() != defaultRequestTimeout {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", defaultRequestTimeout, f.requestTimeout())
+	}
+
+	client := f.newHTTPClient("foo.bar")
+	if client.Timeout != defaultRequestTimeout {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", defaultRequestTimeout, client.Timeout)
+	}
+}
+
+func TestLoadTestBackends(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, "Hostname: pod-%v\n", n%2)
+	}))
+	defer server.Close()
+
+	f := &Framework{
+		IngressController: &ingressController{HTTPURL: server.URL},
+	}
+
+	result, err := f.LoadTest(HTTP, "foo.bar", "/", 4, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Statuses[http.StatusOK] != 10 {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", 10, result.Statuses[http.StatusOK])
+	}
+	expected := map[string]int{"pod-0": 5, "pod-1": 5}
+	if !reflect.DeepEqual(result.Backends, expected) {
+		t.Errorf("expected \"%v\", but \"%v\" was returned", expected, result.Backends)
+	}
+}
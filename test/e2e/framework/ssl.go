@@ -141,12 +141,73 @@ func CreateIngressMASecret(client kubernetes.Interface, host string, secretName,
 	}, nil
 }
 
+// CreateTLSSecret creates or updates a kubernetes.io/tls Secret named after the host in the
+// framework namespace, containing a self-signed certificate valid for the host.
+// The certificate is also stored as ca.crt so clients can trust it (see SecretTLSConfig).
+func (f *Framework) CreateTLSSecret(host string) (*v1.Secret, error) {
+	if len(host) == 0 {
+		return nil, fmt.Errorf("requires a non-empty host")
+	}
+
+	var serverKey, serverCert bytes.Buffer
+	if err := generateRSACert(host, true, &serverKey, &serverCert); err != nil {
+		return nil, err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      host,
+			Namespace: f.Namespace,
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey:       serverCert.Bytes(),
+			v1.TLSPrivateKeyKey: serverKey.Bytes(),
+			"ca.crt":            serverCert.Bytes(),
+		},
+	}
+
+	return f.EnsureSecret(secret), nil
+}
+
+// SecretTLSConfig returns a client TLS configuration trusting the ca.crt of a Secret
+// created by CreateTLSSecret
+func SecretTLSConfig(secret *v1.Secret) (*tls.Config, error) {
+	return tlsConfig(secret.Name, secret.Data["ca.crt"])
+}
+
 // WaitForTLS waits until the TLS handshake with a given server completes successfully.
 func WaitForTLS(url string, tlsConfig *tls.Config) {
 	err := wait.Poll(Poll, 30*time.Second, matchTLSServerName(url, tlsConfig))
 	Expect(err).NotTo(HaveOccurred(), "timeout waiting for TLS configuration in URL %s", url)
 }
 
+// GetServedCertificate returns the leaf certificate presented by NGINX in the
+// HTTPS port when host is used as the TLS server name. The certificate is not verified.
+func (f *Framework) GetServedCertificate(host string) (*x509.Certificate, error) {
+	u, err := net_url.Parse(f.nginxURL(HTTPS))
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: f.requestTimeout()}
+	conn, err := tls.DialWithDialer(dialer, "tcp", u.Host, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error connecting to %v using server name %v: %v", u.Host, host, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate was presented for server name %v", host)
+	}
+
+	return certs[0], nil
+}
+
 // generateRSACert generates a basic self signed certificate using a key length
 // of rsaBits, valid for validFor time.
 func generateRSACert(host string, isCA bool, keyOut, certOut io.Writer) error {
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ExposeTCPService configures NGINX to proxy the TCP port to a service of the framework
+// namespace, using the tcp-services configmap, and adds the port to the ingress-nginx service
+func (f *Framework) ExposeTCPService(port int, service string, servicePort int) error {
+	return f.exposeService("tcp-services", corev1.ProtocolTCP, port, service, servicePort)
+}
+
+// GetNginxTCPPort returns the node port of a TCP port of the ingress-nginx service
+func (f *Framework) GetNginxTCPPort(name string) (int, error) {
+	return f.getNginxProtocolPort(name, corev1.ProtocolTCP)
+}
+
+// TCPRequest sends the payload to a TCP port of the node running NGINX
+// and returns the data received until the connection is closed
+func (f *Framework) TCPRequest(port int, payload string) (string, error) {
+	address := net.JoinHostPort(f.GetNginxIP(), strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", address, defaultTimeout)
+	if err != nil {
+		return "", fmt.Errorf("unexpected error connecting to %v: %v", address, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(defaultTimeout))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = conn.Write([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("unexpected error sending data to %v: %v", address, err)
+	}
+
+	// signal the end of the payload to the backend
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.CloseWrite()
+	}
+
+	b, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return string(b), fmt.Errorf("unexpected error reading data from %v: %v", address, err)
+	}
+
+	return string(b), nil
+}
+
+// ExposeUDPService configures NGINX to proxy the UDP port to a service of the framework
+// namespace, using the udp-services configmap, and adds the port to the ingress-nginx service
+func (f *Framework) ExposeUDPService(port int, service string, servicePort int) error {
+	return f.exposeService("udp-services", corev1.ProtocolUDP, port, service, servicePort)
+}
+
+// GetNginxUDPPort returns the node port of a UDP port of the ingress-nginx service
+func (f *Framework) GetNginxUDPPort(name string) (int, error) {
+	return f.getNginxProtocolPort(name, corev1.ProtocolUDP)
+}
+
+// UDPRequest sends the payload in a datagram to a UDP port of the node running NGINX
+// and returns the first datagram received before the timeout expires
+func (f *Framework) UDPRequest(port int, payload string, timeout time.Duration) (string, error) {
+	address := net.JoinHostPort(f.GetNginxIP(), strconv.Itoa(port))
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return "", fmt.Errorf("unexpected error connecting to %v: %v", address, err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = conn.Write([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("unexpected error sending data to %v: %v", address, err)
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return "", fmt.Errorf("no response received from %v after %v", address, timeout)
+		}
+		return "", fmt.Errorf("unexpected error reading data from %v: %v", address, err)
+	}
+
+	return string(buf[:n]), nil
+}
+
+// exposeService adds a service to the configmap used by NGINX to configure TCP or UDP
+// services and adds the port to the ingress-nginx service
+func (f *Framework) exposeService(configMap string, protocol corev1.Protocol, port int, service string, servicePort int) error {
+	config, err := f.KubeClientSet.
+		CoreV1().
+		ConfigMaps(f.IngressController.Namespace).
+		Get(configMap, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if config.Data == nil {
+		config.Data = map[string]string{}
+	}
+
+	config.Data[strconv.Itoa(port)] = fmt.Sprintf("%v/%v:%v", f.Namespace, service, servicePort)
+	_, err = f.KubeClientSet.
+		CoreV1().
+		ConfigMaps(f.IngressController.Namespace).
+		Update(config)
+	if err != nil {
+		return err
+	}
+
+	svc, err := f.KubeClientSet.
+		CoreV1().
+		Services(f.IngressController.Namespace).
+		Get("ingress-nginx", metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+		Name:       service,
+		Port:       int32(port),
+		TargetPort: intstr.FromInt(port),
+		Protocol:   protocol,
+	})
+	_, err = f.KubeClientSet.
+		CoreV1().
+		Services(f.IngressController.Namespace).
+		Update(svc)
+
+	return err
+}
+
+func (f *Framework) getNginxProtocolPort(name string, protocol corev1.Protocol) (int, error) {
+	s, err := f.KubeClientSet.
+		CoreV1().
+		Services(f.IngressController.Namespace).
+		Get("ingress-nginx", metav1.GetOptions{})
+	if err != nil {
+		return -1, err
+	}
+
+	for _, p := range s.Spec.Ports {
+		// the default protocol of a service port is TCP
+		pp := p.Protocol
+		if pp == "" {
+			pp = corev1.ProtocolTCP
+		}
+
+		if p.NodePort != 0 && p.Name == name && strings.EqualFold(string(pp), string(protocol)) {
+			return int(p.NodePort), nil
+		}
+	}
+
+	return -1, fmt.Errorf("no %v node port named %v in the ingress-nginx service", protocol, name)
+}
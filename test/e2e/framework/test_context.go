@@ -19,6 +19,7 @@ package framework
 import (
 	"flag"
 	"os"
+	"time"
 
 	"github.com/onsi/ginkgo/config"
 
@@ -30,6 +31,9 @@ type TestContextType struct {
 	KubeHost    string
 	KubeConfig  string
 	KubeContext string
+
+	// GRPCDialTimeout limits the time spent connecting to and calling gRPC backends
+	GRPCDialTimeout time.Duration
 }
 
 // TestContext is the global client context for tests.
@@ -53,6 +57,7 @@ func RegisterCommonFlags() {
 	flag.StringVar(&TestContext.KubeHost, "kubernetes-host", "http://127.0.0.1:8080", "The kubernetes host, or apiserver, to connect to")
 	flag.StringVar(&TestContext.KubeConfig, "kubernetes-config", os.Getenv(clientcmd.RecommendedConfigPathEnvVar), "Path to config containing embedded authinfo for kubernetes. Default value is from environment variable "+clientcmd.RecommendedConfigPathEnvVar)
 	flag.StringVar(&TestContext.KubeContext, "kubernetes-context", "", "config context to use for kubernetes. If unset, will use value from 'current-context'")
+	flag.DurationVar(&TestContext.GRPCDialTimeout, "grpc-dial-timeout", 30*time.Second, "Timeout used to connect to and call gRPC backends through the ingress controller")
 }
 
 // RegisterParseFlags registers and parses flags for the test binary.
@@ -41,6 +41,9 @@ const (
 
 	// Default time to wait for operations to complete
 	defaultTimeout = 30 * time.Second
+
+	// Default time to wait for a single HTTP request to complete
+	defaultRequestTimeout = 10 * time.Second
 )
 
 func nowStamp() string {
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Ingress - echo backend", func() {
+	f := framework.NewDefaultFramework("ingress-echo")
+	host := "echo.foo.com"
+
+	It("should route to a named echo backend", func() {
+		err := f.NewEchoDeploymentWithNameAndReplicas("echo-backend", 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "echo-backend", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		status, _, body, err := f.Request(framework.HTTP, host, "/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).Should(Equal(http.StatusOK))
+		Expect(body).Should(ContainSubstring(fmt.Sprintf("host=%v", host)))
+	})
+})
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Ingress - locations", func() {
+	f := framework.NewDefaultFramework("ingress-locations")
+	host := "locations.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should order the most specific location first", func() {
+		f.EnsureIngress(framework.NewSingleIngress("root", "/", host, f.Namespace, "http-svc", 80, nil))
+		f.EnsureIngress(framework.NewSingleIngress("foo", "/foo", host, f.Namespace, "http-svc", 80, nil))
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return strings.Contains(server, "location /foo {") && strings.Contains(server, "location / {")
+			})
+
+		locations, err := f.GetServerLocations(host)
+		Expect(err).NotTo(HaveOccurred())
+
+		index := map[string]int{}
+		for i, l := range locations {
+			index[l] = i
+		}
+		Expect(index).Should(HaveKey("/foo"))
+		Expect(index).Should(HaveKey("/"))
+		Expect(index["/foo"]).Should(BeNumerically("<", index["/"]))
+	})
+})
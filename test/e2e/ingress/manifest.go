@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+const manifest = `
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: manifest-svc
+spec:
+  replicas: 1
+  template:
+    metadata:
+      labels:
+        app: manifest-svc
+    spec:
+      terminationGracePeriodSeconds: 0
+      containers:
+      - name: manifest-svc
+        image: gcr.io/kubernetes-e2e-test-images/echoserver:2.1
+        ports:
+        - containerPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: manifest-svc
+spec:
+  selector:
+    app: manifest-svc
+  ports:
+  - name: http
+    port: 80
+    targetPort: 8080
+`
+
+var _ = framework.IngressNginxDescribe("Ingress - manifest", func() {
+	f := framework.NewDefaultFramework("ingress-manifest")
+	host := "manifest.foo.com"
+
+	It("should route to a backend created from a manifest", func() {
+		err := f.ApplyManifest(manifest)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = f.WaitForEndpoints("manifest-svc", 1, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "manifest-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		status, _, body, err := f.Request(framework.HTTP, host, "/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).Should(Equal(http.StatusOK))
+		Expect(body).Should(ContainSubstring(fmt.Sprintf("host=%v", host)))
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Ingress - namespaces", func() {
+	f := framework.NewDefaultFramework("ingress-namespaces")
+	// the controller watches every namespace, its ingress class keeps it
+	// from serving the ingresses of the tests running in parallel
+	class := "ingress-namespaces"
+	f.ExtraArgs = []string{"--watch-namespace=", fmt.Sprintf("--ingress-class=%v", class)}
+
+	host := "namespaces.foo.com"
+
+	It("should route to a service running in another namespace", func() {
+		appNamespace, err := f.CreateNamespace("ingress-namespaces-app")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(appNamespace).NotTo(Equal(f.IngressController.Namespace))
+
+		f.NewDeploymentInNamespace(appNamespace, "http-svc", "gcr.io/kubernetes-e2e-test-images/echoserver:2.1", 8080, 1)
+
+		annotations := map[string]string{
+			"kubernetes.io/ingress.class": class,
+		}
+		ing := framework.NewSingleIngress(host, "/", host, appNamespace, "http-svc", 80, &annotations)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		// the namespace of the controller does not contain an http-svc service
+		status, _, body, err := f.Request(framework.HTTP, host, "/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).Should(Equal(http.StatusOK))
+		Expect(body).Should(ContainSubstring(fmt.Sprintf("host=%v", host)))
+		Expect(body).Should(ContainSubstring("Hostname: http-svc-"))
+	})
+})
+
+var _ = framework.IngressNginxDescribe("Ingress - controller namespace", func() {
+	f := framework.NewDefaultFramework("ingress-separate")
+	f.SeparateControllerNamespace = true
+
+	host := "separate.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should route ingresses of the test namespace", func() {
+		Expect(f.Namespace).NotTo(Equal(f.IngressController.Namespace))
+
+		pods, err := f.KubeClientSet.CoreV1().Pods(f.Namespace).List(metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=ingress-nginx",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods.Items).Should(BeEmpty(), "unexpected ingress controller pod in the test namespace")
+
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		status, _, _, err := f.Request(framework.HTTP, host, "/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).Should(Equal(http.StatusOK))
+	})
+})
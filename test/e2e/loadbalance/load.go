@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package settings
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Load Balance - concurrent requests", func() {
+	f := framework.NewDefaultFramework("load")
+	host := "load.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should answer concurrent requests sent to an abpolicy ingress", func() {
+		err := f.NewEchoDeploymentWithName("http-svc-canary", "http-svc-canary")
+		Expect(err).NotTo(HaveOccurred())
+
+		annotations := map[string]string{
+			"nginx.ingress.kubernetes.io/abpolicy":          "true",
+			"nginx.ingress.kubernetes.io/abpolicy-host":     host,
+			"nginx.ingress.kubernetes.io/abpolicy-path":     "/",
+			"nginx.ingress.kubernetes.io/abpolicy-type":     "weight",
+			"nginx.ingress.kubernetes.io/abpolicy-backends": `[{"name":"http-svc","weight":50},{"name":"http-svc-canary","weight":50}]`,
+		}
+
+		// both services must be referenced by the ingress to be backends of the policy
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, &annotations)
+		ing.Spec.Rules[0].HTTP.Paths = append(ing.Spec.Rules[0].HTTP.Paths, extensions.HTTPIngressPath{
+			Path: "/canary",
+			Backend: extensions.IngressBackend{
+				ServiceName: "http-svc-canary",
+				ServicePort: intstr.FromInt(80),
+			},
+		})
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		result, err := f.LoadTest(framework.HTTP, host, "/", 10, 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Statuses).Should(Equal(map[int]int{http.StatusOK: 100}))
+
+		// the hostname of the echo server is the name of the pod
+		perService := map[string]int{}
+		for hostname, count := range result.Backends {
+			if strings.HasPrefix(hostname, "http-svc-canary-") {
+				perService["http-svc-canary"] += count
+			} else {
+				perService["http-svc"] += count
+			}
+		}
+		Expect(perService["http-svc"]).Should(BeNumerically(">", 0))
+		Expect(perService["http-svc-canary"]).Should(BeNumerically(">", 0))
+	})
+})
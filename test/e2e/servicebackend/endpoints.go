@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebackend
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Service backend - endpoints", func() {
+	f := framework.NewDefaultFramework("service-endpoints")
+
+	It("should wait for the endpoints of every replica", func() {
+		f.NewEchoDeploymentWithReplicas(2)
+
+		err := f.WaitForEndpoints("http-svc", 2, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		endpoints, err := f.KubeClientSet.CoreV1().Endpoints(f.IngressController.Namespace).Get("http-svc", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints.Subsets).NotTo(BeEmpty())
+		Expect(len(endpoints.Subsets[0].Addresses)).Should(BeNumerically(">=", 2))
+	})
+
+	It("should add the endpoints of new replicas to the upstream", func() {
+		f.NewEchoDeployment()
+
+		host := "upstream.foo.com"
+		ing := framework.NewSingleIngress(host, "/", host, f.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host)))
+			})
+
+		err := framework.UpdateDeployment(f.KubeClientSet, f.Namespace, "http-svc", 2, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = f.WaitForEndpoints("http-svc", 2, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		endpoints, err := f.KubeClientSet.CoreV1().Endpoints(f.Namespace).Get("http-svc", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints.Subsets).NotTo(BeEmpty())
+
+		upstream := fmt.Sprintf("%v-http-svc-80", f.Namespace)
+		for _, address := range endpoints.Subsets[0].Addresses {
+			err := f.WaitForUpstreamServer(upstream, net.JoinHostPort(address.IP, "8080"), time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+})
@@ -81,4 +81,30 @@ var _ = framework.IngressNginxDescribe("Configmap change", func() {
 			})
 		Expect(checksum).NotTo(BeEquivalentTo(newChecksum))
 	})
+
+	It("should restore the configuration after WithNginxConfigMap", func() {
+		host := "configmap-restore"
+
+		ing := framework.NewSingleIngress(host, "/", host, f.IngressController.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		original, err := f.GetNginxConfigMapData()
+		Expect(err).NotTo(HaveOccurred())
+
+		f.WithNginxConfigMap(map[string]string{"proxy-connect-timeout": "20"}, func() {
+			f.WaitForNginxServer(host,
+				func(server string) bool {
+					return strings.Contains(server, "proxy_connect_timeout 20s;")
+				})
+		})
+
+		restored, err := f.GetNginxConfigMapData()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored).Should(Equal(original))
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return strings.Contains(server, "proxy_connect_timeout 5s;")
+			})
+	})
 })
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package settings
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Settings - HTTP2", func() {
+	f := framework.NewDefaultFramework("http2")
+	host := "http2.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should serve requests using HTTP/2", func() {
+		secret, err := f.CreateTLSSecret(host)
+		Expect(err).NotTo(HaveOccurred())
+
+		tlsConfig, err := framework.SecretTLSConfig(secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		ing := framework.NewSingleIngressWithTLS(host, "/", host, f.IngressController.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		framework.WaitForTLS(f.IngressController.HTTPSURL, tlsConfig)
+
+		f.WaitForNginxServer(host,
+			func(server string) bool {
+				return Expect(server).Should(ContainSubstring(fmt.Sprintf("server_name %v", host))) &&
+					Expect(server).Should(ContainSubstring("http2"))
+			})
+
+		resp, body, err := f.HTTP2Request("GET", "/", map[string]string{"Host": host})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+		Expect(resp.Proto).Should(Equal("HTTP/2.0"))
+		Expect(string(body)).Should(ContainSubstring(fmt.Sprintf("host=%v", host)))
+	})
+})
@@ -48,6 +48,8 @@ var _ = framework.IngressNginxDescribe("Server Tokens", func() {
 				return strings.Contains(cfg, "server_tokens off") &&
 					strings.Contains(cfg, "more_clear_headers Server;")
 			})
+
+		f.ExpectResponseHeader(framework.HTTP, serverTokens, "/", "Server", "")
 	})
 
 	It("should exists Server header in the response when is enabled", func() {
@@ -85,5 +87,7 @@ var _ = framework.IngressNginxDescribe("Server Tokens", func() {
 			func(cfg string) bool {
 				return strings.Contains(cfg, "server_tokens on")
 			})
+
+		f.ExpectResponseHeaderContains(framework.HTTP, serverTokens, "/", "Server", "nginx")
 	})
 })
@@ -0,0 +1,54 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package settings
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("Settings - TLS certificate", func() {
+	f := framework.NewDefaultFramework("tls-certificate")
+	host := "certificate.foo.com"
+
+	BeforeEach(func() {
+		f.NewEchoDeployment()
+	})
+
+	It("should serve the certificate of the ingress secret", func() {
+		cert, err := f.GetServedCertificate(host)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.DNSNames).ShouldNot(ContainElement(host))
+
+		secret, err := f.CreateTLSSecret(host)
+		Expect(err).NotTo(HaveOccurred())
+
+		tlsConfig, err := framework.SecretTLSConfig(secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		ing := framework.NewSingleIngressWithTLS(host, "/", host, f.Namespace, "http-svc", 80, nil)
+		f.EnsureIngress(ing)
+
+		framework.WaitForTLS(f.IngressController.HTTPSURL, tlsConfig)
+
+		cert, err = f.GetServedCertificate(host)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.DNSNames).Should(ContainElement(host))
+	})
+})
@@ -82,7 +82,9 @@ var _ = framework.IngressNginxDescribe("Status Update [Status]", func() {
 			})
 
 		framework.Logf("waiting for leader election and initial status update")
-		time.Sleep(30 * time.Second)
+		address, err := f.WaitForIngressAddress(f.IngressController.Namespace, host, 2*time.Minute)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error waiting for the initial status update")
+		Expect(address).NotTo(BeEmpty(), "expected an address in the ingress status")
 
 		err = cmd.Process.Kill()
 		Expect(err).NotTo(HaveOccurred(), "unexpected error terminating kubectl proxy")
@@ -94,4 +94,24 @@ var _ = framework.IngressNginxDescribe("TCP Feature", func() {
 		Expect(errs).Should(BeEmpty())
 		Expect(resp.StatusCode).Should(Equal(200))
 	})
+
+	It("should proxy raw TCP connections", func() {
+		f.NewEchoDeploymentWithReplicas(1)
+
+		err := f.ExposeTCPService(8080, "http-svc", 80)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error exposing TCP service")
+
+		f.WaitForNginxConfiguration(
+			func(cfg string) bool {
+				return strings.Contains(cfg, fmt.Sprintf(`ngx.var.proxy_upstream_name="tcp-%v-http-svc-80"`, f.IngressController.Namespace))
+			})
+
+		port, err := f.GetNginxTCPPort("http-svc")
+		Expect(err).NotTo(HaveOccurred(), "unexpected error obtaning TCP port")
+
+		resp, err := f.TCPRequest(port, "GET /tcp HTTP/1.0\r\nHost: tcp.foo.com\r\n\r\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp).Should(ContainSubstring("200 OK"))
+		Expect(resp).Should(ContainSubstring("host=tcp.foo.com"))
+	})
 })
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/ingress-nginx/test/e2e/framework"
+)
+
+var _ = framework.IngressNginxDescribe("UDP Feature", func() {
+	f := framework.NewDefaultFramework("udp")
+
+	It("should expose a UDP service", func() {
+		f.NewUDPEchoDeployment()
+
+		err := f.ExposeUDPService(9000, "udp-echo", 9000)
+		Expect(err).NotTo(HaveOccurred(), "unexpected error exposing UDP service")
+
+		f.WaitForNginxConfiguration(
+			func(cfg string) bool {
+				return strings.Contains(cfg, fmt.Sprintf(`ngx.var.proxy_upstream_name="udp-%v-udp-echo-9000"`, f.IngressController.Namespace))
+			})
+
+		port, err := f.GetNginxUDPPort("udp-echo")
+		Expect(err).NotTo(HaveOccurred(), "unexpected error obtaning UDP port")
+
+		resp, err := f.UDPRequest(port, "ping", 5*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp).Should(Equal("ping"))
+	})
+})
@@ -19,8 +19,9 @@ set -e
 DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" && pwd )"
 
 export NAMESPACE=$1
+export WATCH_NAMESPACE=${2:-$NAMESPACE}
 
-echo "deploying NGINX Ingress controller in namespace $NAMESPACE"
+echo "deploying NGINX Ingress controller in namespace $NAMESPACE watching namespace $WATCH_NAMESPACE"
 
 function on_exit {
     local error_code="$?"
@@ -32,7 +33,7 @@ function on_exit {
 }
 trap on_exit EXIT
 
-sed "s@\${NAMESPACE}@${NAMESPACE}@" $DIR/../manifests/ingress-controller/mandatory.yaml | kubectl apply --namespace=$NAMESPACE -f -
+sed "s@\${NAMESPACE}@${NAMESPACE}@;s@\${WATCH_NAMESPACE}@${WATCH_NAMESPACE}@" $DIR/../manifests/ingress-controller/mandatory.yaml | kubectl apply --namespace=$NAMESPACE -f -
 cat $DIR/../manifests/ingress-controller/service-nodeport.yaml | kubectl apply --namespace=$NAMESPACE -f -
 
 # wait for the deployment and fail if there is an error before starting the execution of any test